@@ -0,0 +1,55 @@
+package bayes_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	pst "github.com/gnames/bayes/ent/posterior"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbstain(t *testing.T) {
+	nb := bayes.New()
+	nb.Train(cookieJarsFeatures())
+	fs := []ft.Feature{
+		{Name: "CookieF", Value: "chocolate"},
+		{Name: "ColorF", Value: "blue"},
+	}
+
+	// Jar2 has odds 1.5 and probability 0.6, Jar1 has odds 1/1.5.
+	tests := []struct {
+		msg    string
+		opt    bayes.Option
+		reason pst.Reason
+	}{
+		{"no options", bayes.OptMinPosterior(0), ""},
+		{"posterior is high", bayes.OptMinPosterior(0.6), ""},
+		{"posterior is low", bayes.OptMinPosterior(0.7), pst.LowPosterior},
+		{"margin is high", bayes.OptMinMargin(0.8), ""},
+		{"margin is low", bayes.OptMinMargin(0.9), pst.LowMargin},
+		{"enough features", bayes.OptMinKnownFeatures(1), ""},
+		{"few features", bayes.OptMinKnownFeatures(2), pst.FewFeatures},
+	}
+	for _, v := range tests {
+		t.Run(v.msg, func(t *testing.T) {
+			p, err := nb.PosteriorOdds(fs, v.opt)
+			assert.Nil(t, err)
+			assert.Equal(t, ft.Class("Jar2"), p.MaxClass)
+			assert.InDelta(t, 2*math.Log(1.5),
+				p.MaxLogOdds-p.LogOdds["Jar1"], 1e-9)
+			assert.Equal(t, v.reason != "", p.Abstained)
+			assert.Equal(t, v.reason, p.AbstainReason)
+		})
+	}
+
+	t.Run("checks features first", func(t *testing.T) {
+		p, err := nb.PosteriorOdds(fs,
+			bayes.OptMinPosterior(0.7),
+			bayes.OptMinKnownFeatures(2),
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, pst.FewFeatures, p.AbstainReason)
+	})
+}
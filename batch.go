@@ -0,0 +1,227 @@
+package bayes
+
+import (
+	"context"
+	"iter"
+	"runtime"
+	"slices"
+	"sync"
+
+	ft "github.com/gnames/bayes/ent/feature"
+	pst "github.com/gnames/bayes/ent/posterior"
+)
+
+// BatchResult is the result of classification of one set of features
+// from a batch.
+type BatchResult struct {
+	// Odds are posterior odds calculated for the set of features.
+	Odds pst.Odds
+
+	// Err is not nil if the set of features could not be classified.
+	Err error
+}
+
+// PosteriorOddsBatch classifies many sets of features concurrently. The
+// number of workers is set by OptWorkers. Results are returned in the same
+// order as the input. If classification of a set of features fails, its
+// result contains the error, and the rest of the sets are processed as
+// usual.
+func (nb *bayes) PosteriorOddsBatch(
+	fss [][]ft.Feature,
+	opts ...Option,
+) []BatchResult {
+	res, _ := nb.PosteriorOddsBatchContext(context.Background(), fss, opts...)
+	return res
+}
+
+// PosteriorOddsBatchContext is the same as PosteriorOddsBatch, but it
+// stops if the context is cancelled. In such case the sets of features
+// that were not classified get the context's error in their results, and
+// the context's error is returned. If options are not valid, all results
+// get the error of options, and this error is returned.
+func (nb *bayes) PosteriorOddsBatchContext(
+	ctx context.Context,
+	fss [][]ft.Feature,
+	opts ...Option,
+) ([]BatchResult, error) {
+	res := make([]BatchResult, 0, len(fss))
+	cfg := nb.streamConfig(opts)
+	if cfg.err != nil {
+		for range fss {
+			res = append(res, BatchResult{Err: cfg.err})
+		}
+		return res, cfg.err
+	}
+	seq := nb.posteriorOddsSeq(ctx, slices.Values(fss), cfg)
+	for odds, err := range seq {
+		if err != nil && err == ctx.Err() {
+			break
+		}
+		res = append(res, BatchResult{Odds: odds, Err: err})
+	}
+
+	if err := ctx.Err(); err != nil {
+		for len(res) < len(fss) {
+			res = append(res, BatchResult{Err: err})
+		}
+		return res, err
+	}
+	return res, nil
+}
+
+// PosteriorOddsSeq classifies a stream of sets of features concurrently.
+// The results are yielded in the same order as the input. Only a limited
+// number of sets of features is processed at any moment, so the input can
+// be much larger than available memory. The input can block, for example
+// if it reads from a channel. Breaking out of the loop stops the processing
+// of the input and returns at once. If the input is blocked at that moment,
+// it is left to a background goroutine, which stops reading the input as
+// soon as the input yields the next set of features or ends. Options are
+// validated before reading the input, if they are not valid, their error
+// is the only result.
+func (nb *bayes) PosteriorOddsSeq(
+	fss iter.Seq[[]ft.Feature],
+	opts ...Option,
+) iter.Seq2[pst.Odds, error] {
+	return nb.PosteriorOddsSeqContext(context.Background(), fss, opts...)
+}
+
+// PosteriorOddsSeqContext is the same as PosteriorOddsSeq, but it stops if
+// the context is cancelled, even if the input is blocked. In such case the
+// context's error is yielded as the last result.
+func (nb *bayes) PosteriorOddsSeqContext(
+	ctx context.Context,
+	fss iter.Seq[[]ft.Feature],
+	opts ...Option,
+) iter.Seq2[pst.Odds, error] {
+	return func(yield func(pst.Odds, error) bool) {
+		cfg := nb.streamConfig(opts)
+		if cfg.err != nil {
+			yield(pst.Odds{}, cfg.err)
+			return
+		}
+		for odds, err := range nb.posteriorOddsSeq(ctx, fss, cfg) {
+			if !yield(odds, err) {
+				return
+			}
+		}
+	}
+}
+
+// streamConfig creates the config that is shared by all sets of features
+// of a batch or a stream, so options are validated only once. Priors
+// changed by options are calculated from the training data at this moment.
+func (nb *bayes) streamConfig(opts []Option) config {
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
+
+	return nb.newConfig(opts)
+}
+
+// posteriorOddsSeq classifies a stream of sets of features with a config
+// that has no errors.
+func (nb *bayes) posteriorOddsSeq(
+	ctx context.Context,
+	fss iter.Seq[[]ft.Feature],
+	cfg config,
+) iter.Seq2[pst.Odds, error] {
+	return func(yield func(pst.Odds, error) bool) {
+		workers := cfg.workers
+		if workers < 1 {
+			workers = runtime.NumCPU()
+		}
+
+		type job struct {
+			fs  []ft.Feature
+			res chan BatchResult
+		}
+		// workers are finished before returning. The reader of the input is
+		// not waited for, because the input might block.
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		done := make(chan struct{})
+		defer close(done)
+		jobs := make(chan job)
+		// queue keeps results of jobs in the order of the input.
+		queue := make(chan chan BatchResult, 2*workers)
+
+		go func() {
+			defer close(jobs)
+			defer close(queue)
+			for fs := range fss {
+				j := job{fs: fs, res: make(chan BatchResult, 1)}
+				select {
+				case queue <- j.res:
+				case <-done:
+					return
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case jobs <- j:
+				case <-done:
+					return
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					var j job
+					var ok bool
+					select {
+					case j, ok = <-jobs:
+					case <-done:
+						return
+					}
+					if !ok {
+						return
+					}
+					if err := ctx.Err(); err != nil {
+						j.res <- BatchResult{Err: err}
+						continue
+					}
+					nb.mu.RLock()
+					odds, err := nb.posteriorOdds(j.fs, cfg)
+					nb.mu.RUnlock()
+					j.res <- BatchResult{Odds: odds, Err: err}
+				}
+			}()
+		}
+
+		for {
+			var res chan BatchResult
+			var ok bool
+			select {
+			case res, ok = <-queue:
+			case <-ctx.Done():
+				yield(pst.Odds{}, ctx.Err())
+				return
+			}
+			if !ok {
+				break
+			}
+			var r BatchResult
+			select {
+			case r = <-res:
+			case <-ctx.Done():
+				r = BatchResult{Err: ctx.Err()}
+			}
+			if r.Err != nil && r.Err == ctx.Err() {
+				yield(pst.Odds{}, r.Err)
+				return
+			}
+			if !yield(r.Odds, r.Err) {
+				return
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			yield(pst.Odds{}, err)
+		}
+	}
+}
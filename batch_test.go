@@ -0,0 +1,257 @@
+package bayes_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPosteriorOddsBatch(t *testing.T) {
+	nb := bayes.New()
+	nb.Train(threeCookieJarsFeatures())
+	fss := batchFeatures(500)
+
+	for _, workers := range []int{0, 1, 3, 16} {
+		t.Run(fmt.Sprintf("workers %d", workers), func(t *testing.T) {
+			res := nb.PosteriorOddsBatch(fss, bayes.OptWorkers(workers))
+			assert.Equal(t, len(fss), len(res))
+			for i := range fss {
+				p, err := nb.PosteriorOdds(fss[i])
+				if err != nil {
+					assert.Equal(t, err, res[i].Err)
+					continue
+				}
+				assert.Nil(t, res[i].Err)
+				assert.Equal(t, p.MaxClass, res[i].Odds.MaxClass)
+				assert.Equal(t, p.LogOdds, res[i].Odds.LogOdds)
+			}
+		})
+	}
+
+	t.Run("applies options", func(t *testing.T) {
+		res := nb.PosteriorOddsBatch(fss[:4], bayes.OptLogOnly(true))
+		for _, v := range res {
+			assert.Nil(t, v.Odds.ClassOdds)
+		}
+	})
+
+	t.Run("returns errors per item", func(t *testing.T) {
+		res := nb.PosteriorOddsBatch(fss[:10])
+		for i, v := range res {
+			if i%5 == 4 {
+				assert.EqualError(t, v.Err, "all features are unknown")
+			} else {
+				assert.Nil(t, v.Err)
+			}
+		}
+	})
+
+	t.Run("returns errors of options for every item", func(t *testing.T) {
+		opt := bayes.OptPriorProfile("missing")
+		res, err := nb.PosteriorOddsBatchContext(
+			context.Background(), fss[:3], opt,
+		)
+		assert.EqualError(t, err, "unknown prior profile 'missing'")
+		assert.Equal(t, 3, len(res))
+		for _, v := range res {
+			assert.Equal(t, err, v.Err)
+		}
+	})
+
+	t.Run("works with empty input", func(t *testing.T) {
+		res := nb.PosteriorOddsBatch(nil)
+		assert.Equal(t, 0, len(res))
+	})
+}
+
+func TestPosteriorOddsSeq(t *testing.T) {
+	nb := bayes.New()
+	nb.Train(threeCookieJarsFeatures())
+	fss := batchFeatures(1000)
+
+	t.Run("yields results in order", func(t *testing.T) {
+		var i int
+		seq := nb.PosteriorOddsSeq(slices.Values(fss), bayes.OptWorkers(4))
+		for p, err := range seq {
+			exp, expErr := nb.PosteriorOdds(fss[i])
+			assert.Equal(t, expErr, err)
+			assert.Equal(t, exp.MaxClass, p.MaxClass)
+			i++
+		}
+		assert.Equal(t, len(fss), i)
+	})
+
+	t.Run("yields one error for invalid options", func(t *testing.T) {
+		var read, n int
+		input := func(yield func([]ft.Feature) bool) {
+			for _, fs := range fss {
+				read++
+				if !yield(fs) {
+					return
+				}
+			}
+		}
+		opt := bayes.OptPriorProfile("missing")
+		for _, err := range nb.PosteriorOddsSeq(input, opt) {
+			assert.EqualError(t, err, "unknown prior profile 'missing'")
+			n++
+		}
+		assert.Equal(t, 1, n)
+		assert.Equal(t, 0, read)
+	})
+
+	t.Run("stops on break", func(t *testing.T) {
+		// the input might be read in the background after the loop.
+		var read atomic.Int64
+		input := func(yield func([]ft.Feature) bool) {
+			for _, fs := range fss {
+				read.Add(1)
+				if !yield(fs) {
+					return
+				}
+			}
+		}
+
+		var i int
+		for range nb.PosteriorOddsSeq(input, bayes.OptWorkers(2)) {
+			i++
+			if i == 10 {
+				break
+			}
+		}
+		assert.Equal(t, 10, i)
+		assert.Less(t, read.Load(), int64(len(fss)))
+	})
+
+	t.Run("stops on break with blocked input", func(t *testing.T) {
+		ch := make(chan []ft.Feature)
+		input := func(yield func([]ft.Feature) bool) {
+			for fs := range ch {
+				if !yield(fs) {
+					return
+				}
+			}
+		}
+		go func() { ch <- fss[0] }()
+
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+			for range nb.PosteriorOddsSeq(input, bayes.OptWorkers(2)) {
+				break
+			}
+		}()
+		select {
+		case <-finished:
+		case <-time.After(5 * time.Second):
+			t.Fatal("PosteriorOddsSeq did not return after break")
+		}
+		close(ch)
+	})
+}
+
+func TestBatchContext(t *testing.T) {
+	nb := bayes.New()
+	nb.Train(threeCookieJarsFeatures())
+	fss := batchFeatures(1000)
+
+	t.Run("classifies with context", func(t *testing.T) {
+		res, err := nb.PosteriorOddsBatchContext(context.Background(), fss[:20])
+		assert.Nil(t, err)
+		assert.Equal(t, nb.PosteriorOddsBatch(fss[:20]), res)
+	})
+
+	t.Run("stops batch on cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		res, err := nb.PosteriorOddsBatchContext(ctx, fss)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, len(fss), len(res))
+		assert.ErrorIs(t, res[len(res)-1].Err, context.Canceled)
+	})
+
+	t.Run("stops stream on cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// the input might be read in the background after the loop.
+		var read atomic.Int64
+		input := func(yield func([]ft.Feature) bool) {
+			for _, fs := range fss {
+				if read.Add(1) == 100 {
+					cancel()
+				}
+				if !yield(fs) {
+					return
+				}
+			}
+		}
+
+		var count int
+		var lastErr error
+		seq := nb.PosteriorOddsSeqContext(ctx, input, bayes.OptWorkers(2))
+		for _, err := range seq {
+			count++
+			lastErr = err
+		}
+		assert.ErrorIs(t, lastErr, context.Canceled)
+		assert.Less(t, count, len(fss))
+		assert.Less(t, read.Load(), int64(len(fss)))
+	})
+
+	t.Run("stops blocked stream on deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(
+			context.Background(), 50*time.Millisecond,
+		)
+		defer cancel()
+		ch := make(chan []ft.Feature)
+		defer close(ch)
+		input := func(yield func([]ft.Feature) bool) {
+			for fs := range ch {
+				if !yield(fs) {
+					return
+				}
+			}
+		}
+
+		errs := make(chan error, 1)
+		go func() {
+			var lastErr error
+			for _, err := range nb.PosteriorOddsSeqContext(ctx, input) {
+				lastErr = err
+			}
+			errs <- lastErr
+		}()
+		select {
+		case err := <-errs:
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		case <-time.After(5 * time.Second):
+			t.Fatal("PosteriorOddsSeqContext did not stop on deadline")
+		}
+	})
+}
+
+// batchFeatures creates sets of features for batch classification. Every
+// fifth set contains only unknown features.
+func batchFeatures(n int) [][]ft.Feature {
+	vals := []ft.Value{"chocolate", "plain"}
+	shapes := []ft.Value{"star", "round"}
+	res := make([][]ft.Feature, n)
+	for i := range res {
+		if i%5 == 4 {
+			res[i] = []ft.Feature{{Name: "UnknownF", Value: "none"}}
+			continue
+		}
+		res[i] = []ft.Feature{
+			{Name: "CookieF", Value: vals[i%2]},
+			{Name: "ShapeF", Value: shapes[(i/2)%2]},
+		}
+	}
+	return res
+}
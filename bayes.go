@@ -3,83 +3,337 @@ package bayes
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
 
+	"github.com/gnames/bayes/ent/calibration"
+	"github.com/gnames/bayes/ent/discretize"
+	"github.com/gnames/bayes/ent/distribution"
 	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/gnames/bayes/ent/prior"
+	"github.com/gnames/bayes/ent/smoothing"
 )
 
 type bayes struct {
+	// mu protects training data from concurrent changes during
+	// classification.
+	mu sync.RWMutex
+
 	// classes are a classifier categories. There must be at least 2 classes.
 	classes []ft.Class
 
 	// casesTotal is the number of entries in the training set including
-	// all classes.
-	casesTotal int
+	// all classes. Entries are counted according to their weights.
+	casesTotal float64
 
 	// classCases is the number of entities per one class.
-	classCases map[ft.Class]int
+	classCases map[ft.Class]float64
 
 	// featureCases is the number of entities per a particular feature.
-	featureCases map[ft.Feature]map[ft.Class]int
+	featureCases map[ft.Feature]map[ft.Class]float64
 
 	// featureTotal is the number of all entities for a perticular feature.
-	featureTotal map[ft.Feature]int
+	featureTotal map[ft.Feature]float64
+
+	// vocab contains statistics about values of feature names.
+	vocab vocabulary
+
+	// model is the kind of Naive Bayes model.
+	model Model
+
+	// complement are normalization factors of weights of features for
+	// every class. They are used by Complement model.
+	complement map[ft.Class]float64
+
+	// priors provide prior probabilities of classes for classification
+	// calls. If it is nil, prior probabilities from training are used.
+	priors prior.Provider
+
+	// profiles are named numbers of cases of classes that replace the
+	// ones from training when selected by OptPriorProfile.
+	profiles map[string]map[ft.Class]float64
+
+	// calibration corrects probabilities of classes. It is nil if
+	// probabilities are not calibrated.
+	calibration *calibration.Calibrator
+
+	// smoothing is the algorithm that estimates probabilities of features
+	// from their counts.
+	smoothing smoothing.Smoothing
+
+	// numeric are names of numeric features with kinds of their
+	// distributions.
+	numeric map[ft.Name]distribution.Kind
+
+	// numStats are statistics of values of numeric features per class.
+	numStats map[ft.Name]map[ft.Class]distribution.Stats
+
+	// numRest are statistics of values of numeric features of all classes
+	// except the given one. They are updated with numStats, so
+	// classification does not need to merge statistics.
+	numRest map[ft.Name]map[ft.Class]distribution.Stats
+
+	// numTotal are statistics of all values of numeric features. They
+	// limit variances of distributions of classes from below.
+	numTotal map[ft.Name]distribution.Stats
+
+	// binning are names of numeric features that are converted to
+	// categorical ones by bins, with settings for learning the bins.
+	binning map[ft.Name]discretize.Binning
+
+	// bernoulli are names of binary features. Values of such features that
+	// are absent from a feature set are evidence too.
+	bernoulli map[ft.Name]struct{}
+
+	// parents are parents of classes in a hierarchy of classes.
+	parents map[ft.Class]ft.Class
 
-	// tmpClassCases is used to provide a new prior odds.
-	tmpClassCases map[ft.Class]int
+	// boundaries are learned boundaries of bins of numeric features. The
+	// map is never changed, it is replaced when new boundaries are learned.
+	boundaries map[ft.Name][]float64
 
-	// tmpCasesTotal is used to provide a new prior odds.
-	tmpCasesTotal int
+	// version changes every time when the way of counting training data
+	// changes, for example when boundaries of bins are learned.
+	version int
 
-	// ignorePriorOdds indicates that likelihood will be calculated without
-	// taking in account prior odds.
-	ignorePriorOdds bool
+	// err is an error in options of New, for example an unknown model or
+	// a cycle in the hierarchy of classes. Training and classification
+	// return it. It is cleared by loading a valid dump.
+	err error
 }
 
 // New creates a new instance of Bayes object. This object needs to get data
 // from either training or from loading a dump of previous training data.
-func New() Bayes {
+func New(opts ...ModelOption) Bayes {
 	nb := &bayes{
-		classCases:   make(map[ft.Class]int),
-		featureCases: make(map[ft.Feature]map[ft.Class]int),
-		featureTotal: make(map[ft.Feature]int),
+		model:     Categorical,
+		smoothing: smoothing.Crude{},
+		numeric:   make(map[ft.Name]distribution.Kind),
+		binning:   make(map[ft.Name]discretize.Binning),
+		bernoulli: make(map[ft.Name]struct{}),
+		parents:   make(map[ft.Class]ft.Class),
+		profiles:  make(map[string]map[ft.Class]float64),
 	}
+	for _, opt := range opts {
+		opt(nb)
+	}
+	nb.err = nb.checkOptions()
+	nb.reset()
 	return nb
 }
 
+// checkOptions returns an error if options of New are not valid.
+func (nb *bayes) checkOptions() error {
+	if nb.smoothing == nil {
+		// the default smoothing keeps Inspect and Dump working.
+		nb.smoothing = smoothing.Crude{}
+		return errors.New("smoothing is not set")
+	}
+	s := nb.smoothing
+	if _, err := smoothing.New(s.Name(), s.Param()); err != nil {
+		return err
+	}
+	if _, err := NewModel(string(nb.model)); err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(nb.binning)) {
+		if err := nb.binning[name].Check(); err != nil {
+			return fmt.Errorf("cannot bin feature '%s': %w", name, err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(nb.numeric)) {
+		kind := string(nb.numeric[name])
+		if _, err := distribution.NewKind(kind); err != nil {
+			return err
+		}
+	}
+	return checkParents(nb.parents)
+}
+
+// reset removes all training data from the object.
+func (nb *bayes) reset() {
+	nb.classes = nil
+	nb.casesTotal = 0
+	nb.classCases = make(map[ft.Class]float64)
+	nb.featureCases = make(map[ft.Feature]map[ft.Class]float64)
+	nb.featureTotal = make(map[ft.Feature]float64)
+	nb.vocab = newVocabulary()
+	nb.numStats = make(map[ft.Name]map[ft.Class]distribution.Stats)
+	nb.numRest = make(map[ft.Name]map[ft.Class]distribution.Stats)
+	nb.numTotal = make(map[ft.Name]distribution.Stats)
+	nb.boundaries = make(map[ft.Name][]float64)
+	nb.complement = make(map[ft.Class]float64)
+}
+
+// replace substitutes training data and settings with the ones from
+// another object.
+func (nb *bayes) replace(src *bayes) {
+	nb.classes = src.classes
+	nb.casesTotal = src.casesTotal
+	nb.classCases = src.classCases
+	nb.featureCases = src.featureCases
+	nb.featureTotal = src.featureTotal
+	nb.vocab = src.vocab
+	nb.model = src.model
+	nb.complement = src.complement
+	nb.smoothing = src.smoothing
+	nb.numeric = src.numeric
+	nb.numStats = src.numStats
+	nb.numRest = src.numRest
+	nb.numTotal = src.numTotal
+	nb.binning = src.binning
+	nb.bernoulli = src.bernoulli
+	nb.parents = src.parents
+	nb.profiles = src.profiles
+	nb.calibration = src.calibration
+	nb.boundaries = src.boundaries
+	nb.err = src.err
+	nb.version++
+}
+
 // PriorOdds returns prior odds calculated from the training set
 func (nb *bayes) PriorOdds(l ft.Class) (float64, error) {
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
+
 	return odds(l, nb.classCases, nb.casesTotal)
 }
 
 func odds(
 	l ft.Class,
-	lc map[ft.Class]int,
-	casesTotal int,
+	lc map[ft.Class]float64,
+	casesTotal float64,
 ) (float64, error) {
-	var freq int
+	var freq float64
 	var ok bool
 	if freq, ok = lc[l]; !ok {
 		return 0, fmt.Errorf("unknown class '%s'", l)
 	}
-	pL := float64(freq) / float64(casesTotal)
+	pL := freq / casesTotal
 	if pL == 1 || casesTotal == 0 {
 		return 0, errors.New("infinite prior odds")
 	}
 	return pL / (1 - pL), nil
 }
 
-func (b *bayes) Odds(lfs ft.ClassFeatures) (float64, error) {
-	return 0, nil
+// Odds returns posterior odds of the class of a feature set given its
+// features. It takes the same options as PosteriorOdds.
+func (nb *bayes) Odds(lfs ft.ClassFeatures, opts ...Option) (float64, error) {
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
+
+	if err := nb.checkClass(lfs.Class); err != nil {
+		return 0, fmt.Errorf("unknown class '%s'", lfs.Class)
+	}
+	if nb.model == Complement {
+		return 0, errors.New("complement model does not calculate odds")
+	}
+	res, err := nb.posteriorOdds(lfs.Features, nb.newConfig(opts))
+	if err != nil {
+		return 0, err
+	}
+	return math.Exp(res.LogOdds[lfs.Class]), nil
+}
+
+// binFeatures replaces values of numeric features that are converted to
+// bins with labels of their bins. Features without learned bins stay
+// unchanged.
+func (nb *bayes) binFeatures(fs []ft.Feature) ([]ft.Feature, error) {
+	if len(nb.binning) == 0 {
+		return fs, nil
+	}
+	res := slices.Clone(fs)
+	for i, f := range fs {
+		if _, ok := nb.binning[f.Name]; !ok {
+			continue
+		}
+		x, err := parseBinned(f)
+		if err != nil {
+			return nil, err
+		}
+		bounds, ok := nb.boundaries[f.Name]
+		if !ok {
+			continue
+		}
+		res[i].Value = discretize.Label(bounds, x)
+	}
+	return res, nil
 }
 
 func (nb *bayes) checkFeature(f ft.Feature) error {
-	var ok bool
-	if _, ok = nb.featureCases[f]; !ok {
+	if kind, ok := nb.numeric[f.Name]; ok {
+		if _, err := parseNumeric(kind, f); err != nil {
+			return err
+		}
+	}
+	if !nb.knownFeature(f) {
 		return fmt.Errorf("no feature with name '%s' and value '%s'", f.Name, f.Value)
 	}
 	return nil
 }
 
+// knownFeature returns true if training data contain the feature. Numeric
+// features are known if training data contain any value of their name.
+func (nb *bayes) knownFeature(f ft.Feature) bool {
+	if _, ok := nb.numeric[f.Name]; ok {
+		return len(nb.numStats[f.Name]) > 0
+	}
+	_, ok := nb.featureCases[f]
+	return ok
+}
+
+// updateNumRest recalculates statistics of numeric features of all classes
+// and of all classes except one from numStats.
+func (nb *bayes) updateNumRest() {
+	res := make(map[ft.Name]map[ft.Class]distribution.Stats, len(nb.numStats))
+	totals := make(map[ft.Name]distribution.Stats, len(nb.numStats))
+	for name, sv := range nb.numStats {
+		var total distribution.Stats
+		for _, class := range nb.classes {
+			total = total.Add(sv[class])
+		}
+		totals[name] = total
+		res[name] = make(map[ft.Class]distribution.Stats, len(nb.classes))
+		for _, class := range nb.classes {
+			res[name][class] = total.Sub(sv[class], epsilon).Smooth(total)
+		}
+	}
+	nb.numRest = res
+	nb.numTotal = totals
+}
+
+// parseNumeric converts a value of a numeric feature to a number.
+func parseNumeric(kind distribution.Kind, f ft.Feature) (float64, error) {
+	x, err := strconv.ParseFloat(string(f.Value), 64)
+	if err == nil {
+		err = kind.Check(x)
+	}
+	if err != nil {
+		return 0, fmt.Errorf(
+			"invalid value '%s' of numeric feature '%s': %w", f.Value, f.Name, err,
+		)
+	}
+	return x, nil
+}
+
+// parseBinned converts a value of a numeric feature that is converted to
+// bins to a number.
+func parseBinned(f ft.Feature) (float64, error) {
+	x, err := strconv.ParseFloat(string(f.Value), 64)
+	if err == nil && math.IsNaN(x) {
+		err = errors.New("value is not a number")
+	}
+	if err != nil {
+		return 0, fmt.Errorf(
+			"invalid value '%s' of numeric feature '%s': %w", f.Value, f.Name, err,
+		)
+	}
+	return x, nil
+}
+
 func (nb *bayes) checkClass(l ft.Class) error {
 	if _, ok := nb.classCases[l]; !ok {
 		return fmt.Errorf("there is no label '%s'", l)
@@ -87,7 +341,9 @@ func (nb *bayes) checkClass(l ft.Class) error {
 	return nil
 }
 
+// featTotal recalculates featureTotal from featureCases.
 func (nb *bayes) featTotal() {
+	nb.featureTotal = make(map[ft.Feature]float64)
 	for fk, fv := range nb.featureCases {
 		for _, v := range fv {
 			nb.featureTotal[fk] += v
@@ -1,12 +1,18 @@
 package bayes_test
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"math"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gnames/bayes"
 	ft "github.com/gnames/bayes/ent/feature"
+	pst "github.com/gnames/bayes/ent/posterior"
+	"github.com/gnames/bayes/ent/smoothing"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -28,11 +34,11 @@ func TestTrain(t *testing.T) {
 	assert.Equal(t, 2, len(o.Classes))
 
 	plain := o.FeatureCases["CookieF"]["plain"]
-	assert.Equal(t, 30, plain["Jar1"])
+	assert.Equal(t, 30.0, plain["Jar1"])
 
 	chocolate := o.FeatureCases["CookieF"]["chocolate"]
-	assert.Equal(t, 15, chocolate["Jar2"])
-	assert.Equal(t, 40, o.ClassCases["Jar1"])
+	assert.Equal(t, 15.0, chocolate["Jar2"])
+	assert.Equal(t, 40.0, o.ClassCases["Jar1"])
 }
 
 // TestDumpLoad dumps the content of NaiveBayes object to json file
@@ -47,11 +53,332 @@ func TestDumpLoad(t *testing.T) {
 	err = nb2.Load(json)
 	assert.Nil(t, err)
 	o := nb2.Inspect()
-	assert.Equal(t, 70, o.CasesTotal)
+	assert.Equal(t, 70.0, o.CasesTotal)
 	assert.Equal(t, 2, len(o.Classes))
-	assert.Equal(t, 40, o.ClassCases["Jar1"])
-	assert.Equal(t, 10, o.FeatureCases["CookieF"]["chocolate"]["Jar1"])
-	assert.Equal(t, 30, o.FeatureCases["CookieF"]["plain"]["Jar1"])
+	assert.Equal(t, 40.0, o.ClassCases["Jar1"])
+	assert.Equal(t, 10.0, o.FeatureCases["CookieF"]["chocolate"]["Jar1"])
+	assert.Equal(t, 30.0, o.FeatureCases["CookieF"]["plain"]["Jar1"])
+}
+
+func TestTrainIncremental(t *testing.T) {
+	tests := []struct {
+		msg string
+		lfs []ft.ClassFeatures
+	}{
+		{"2 classes", cookieJarsFeatures()},
+		{"3 classes", threeCookieJarsFeatures()},
+	}
+
+	for _, v := range tests {
+		t.Run(v.msg, func(t *testing.T) {
+			batch := bayes.New()
+			batch.Train(v.lfs)
+
+			incr := bayes.New()
+			for i := 0; i < len(v.lfs); i += 7 {
+				end := min(i+7, len(v.lfs))
+				incr.Train(v.lfs[i:end])
+			}
+			assertSameModels(t, batch, incr)
+
+			half := len(v.lfs) / 2
+			nb := bayes.New()
+			nb.Train(v.lfs[:half])
+			dump, err := nb.Dump()
+			assert.Nil(t, err)
+
+			loaded := bayes.New()
+			err = loaded.Load(dump)
+			assert.Nil(t, err)
+			loaded.Train(v.lfs[half:])
+			assertSameModels(t, batch, loaded)
+		})
+	}
+}
+
+func TestLoadReplacesData(t *testing.T) {
+	nb := bayes.New()
+	nb.Train(cookieJarsFeatures())
+	dump, err := nb.Dump()
+	assert.Nil(t, err)
+
+	nb2 := bayes.New()
+	nb2.Train(threeCookieJarsFeatures())
+	err = nb2.Load(dump)
+	assert.Nil(t, err)
+	assertSameModels(t, nb, nb2)
+}
+
+func TestUntrain(t *testing.T) {
+	t.Run("removes a class", func(t *testing.T) {
+		lfs := threeCookieJarsFeatures()
+		nb := bayes.New()
+		nb.Train(lfs)
+		err := nb.Untrain(lfs[70:])
+		assert.Nil(t, err)
+
+		nb2 := bayes.New()
+		nb2.Train(cookieJarsFeatures())
+		assertSameModels(t, nb2, nb)
+		_, err = nb.PriorOdds(ft.Class("Jar3"))
+		assert.Equal(t, "unknown class 'Jar3'", err.Error())
+	})
+
+	t.Run("removes one case", func(t *testing.T) {
+		lfs := cookieJarsFeatures()
+		nb := bayes.New()
+		nb.Train(lfs)
+		err := nb.Untrain(lfs[:1])
+		assert.Nil(t, err)
+
+		nb2 := bayes.New()
+		nb2.Train(lfs[1:])
+		assertSameModels(t, nb2, nb)
+		o := nb.Inspect()
+		assert.Equal(t, 69.0, o.CasesTotal)
+		assert.Equal(t, 9.0, o.FeatureCases["CookieF"]["chocolate"]["Jar1"])
+	})
+
+	t.Run("removes features without cases", func(t *testing.T) {
+		lfs := threeCookieJarsFeatures()
+		nb := bayes.New()
+		nb.Train(lfs)
+		err := nb.Untrain(lfs[:40])
+		assert.Nil(t, err)
+		o := nb.Inspect()
+		assert.NotContains(t, o.FeatureCases["ShapeF"], "star")
+		assert.NotContains(t, o.FeatureCases["CookieF"]["plain"], "Jar1")
+		_, err = nb.Likelihood(
+			ft.Feature{Name: "ShapeF", Value: "star"}, ft.Class("Jar2"),
+		)
+		assert.EqualError(t, err, "no feature with name 'ShapeF' and value 'star'")
+	})
+
+	t.Run("does not allow negative counts", func(t *testing.T) {
+		lfs := cookieJarsFeatures()
+		nb := bayes.New()
+		nb.Train(lfs[:40])
+		err := nb.Untrain(lfs[:41])
+		assert.EqualError(t, err,
+			"cannot remove 1 cases of class 'Jar2', there are only 0")
+
+		lf := ft.ClassFeatures{
+			Class:    ft.Class("Jar1"),
+			Features: []ft.Feature{{Name: "ShapeF", Value: "round"}},
+		}
+		err = nb.Untrain([]ft.ClassFeatures{lf})
+		assert.EqualError(t, err,
+			"cannot remove 1 cases of feature with name 'ShapeF' and "+
+				"value 'round' from class 'Jar1', there are only 0")
+
+		nb2 := bayes.New()
+		nb2.Train(lfs[:40])
+		assert.Equal(t, nb2.Inspect(), nb.Inspect())
+	})
+
+	t.Run("does not leave features of removed classes", func(t *testing.T) {
+		x := func(v ft.Value) []ft.Feature {
+			return []ft.Feature{{Name: "x", Value: v}}
+		}
+		lfs := []ft.ClassFeatures{
+			{Class: "A", Features: x("1")},
+			{Class: "A", Features: x("2")},
+			{Class: "B", Features: x("1")},
+			{Class: "C", Features: x("2")},
+		}
+		nb := bayes.New()
+		nb.Train(lfs)
+		err := nb.Untrain([]ft.ClassFeatures{
+			{Class: "A", Features: x("1")},
+			{Class: "A"},
+		})
+		assert.EqualError(t, err,
+			"cannot remove all cases of class 'A', feature with name 'x' "+
+				"and value '2' still has 1 cases of the class")
+		assert.Equal(t, []string{"A", "B", "C"}, nb.Inspect().Classes)
+
+		err = nb.Untrain(lfs[:2])
+		assert.Nil(t, err)
+		o := nb.Inspect()
+		assert.Equal(t, []string{"B", "C"}, o.Classes)
+		assert.NotContains(t, o.FeatureCases["x"]["2"], "A")
+	})
+}
+
+func TestTrainWeights(t *testing.T) {
+	t.Run("weight is the same as repeated cases", func(t *testing.T) {
+		lfs := cookieJarsFeatures()
+		nb := bayes.New()
+		nb.Train(append(lfs, lfs[:10]...))
+
+		wlfs := cookieJarsFeatures()
+		for i := range wlfs[:10] {
+			wlfs[i].Weight = ft.Weight(2)
+		}
+		nb2 := bayes.New()
+		err := nb2.Train(wlfs)
+		assert.Nil(t, err)
+		assertSameModels(t, nb, nb2)
+	})
+
+	t.Run("weights can be fractional", func(t *testing.T) {
+		lfs := cookieJarsFeatures()
+		for i := range lfs[40:] {
+			lfs[40+i].Weight = ft.Weight(0.5)
+		}
+		nb := bayes.New()
+		err := nb.Train(lfs)
+		assert.Nil(t, err)
+		o := nb.Inspect()
+		assert.Equal(t, 55.0, o.CasesTotal)
+		assert.Equal(t, 15.0, o.ClassCases["Jar2"])
+		odds, err := nb.PriorOdds(ft.Class("Jar1"))
+		assert.Nil(t, err)
+		assert.InDelta(t, 2.667, odds, 0.01)
+
+		dump, err := nb.Dump()
+		assert.Nil(t, err)
+		nb2 := bayes.New()
+		err = nb2.Load(dump)
+		assert.Nil(t, err)
+		assertSameModels(t, nb, nb2)
+
+		err = nb.Untrain(lfs[40:])
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"Jar1"}, nb.Inspect().Classes)
+	})
+
+	t.Run("zero weight does not count", func(t *testing.T) {
+		lfs := cookieJarsFeatures()
+		nb := bayes.New()
+		nb.Train(lfs)
+
+		zero := []ft.ClassFeatures{
+			{Class: "Jar1", Features: lfs[0].Features, Weight: ft.Weight(0)},
+			{Class: "Jar3", Features: lfs[0].Features, Weight: ft.Weight(0)},
+		}
+		nb2 := bayes.New()
+		err := nb2.Train(append(cookieJarsFeatures(), zero...))
+		assert.Nil(t, err)
+		assertSameModels(t, nb, nb2)
+		assert.Equal(t, []string{"Jar1", "Jar2"}, nb2.Inspect().Classes)
+
+		err = nb2.Untrain(zero)
+		assert.Nil(t, err)
+		assertSameModels(t, nb, nb2)
+	})
+
+	t.Run("does not allow negative weights", func(t *testing.T) {
+		lfs := cookieJarsFeatures()
+		lfs[5].Weight = ft.Weight(-1)
+		nb := bayes.New()
+		err := nb.Train(lfs)
+		assert.EqualError(t, err, "invalid weight -1 for class 'Jar1'")
+		assert.Equal(t, 0.0, nb.Inspect().CasesTotal)
+	})
+
+	t.Run("loads integer dumps", func(t *testing.T) {
+		dump := `{
+  "classes": ["Jar1", "Jar2"],
+  "casesTotal": 70,
+  "classCases": {"Jar1": 40, "Jar2": 30},
+  "featureCases": {
+    "CookieF": {
+      "chocolate": {"Jar1": 10, "Jar2": 15},
+      "plain": {"Jar1": 30, "Jar2": 15}
+    },
+    "ShapeF": {
+      "round": {"Jar2": 30},
+      "star": {"Jar1": 40}
+    }
+  }
+}`
+		nb := bayes.New()
+		err := nb.Load([]byte(dump))
+		assert.Nil(t, err)
+
+		nb2 := bayes.New()
+		nb2.Train(cookieJarsFeatures())
+		assertSameModels(t, nb2, nb)
+	})
+}
+
+func TestContext(t *testing.T) {
+	lfs := cookieJarsFeatures()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("trains with context", func(t *testing.T) {
+		nb := bayes.New()
+		err := nb.TrainContext(context.Background(), lfs)
+		assert.Nil(t, err)
+		assert.Equal(t, 70.0, nb.Inspect().CasesTotal)
+	})
+
+	t.Run("does not change data on cancelled training", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(lfs[:10])
+		err := nb.TrainContext(cancelled, lfs)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 10.0, nb.Inspect().CasesTotal)
+
+		ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+		defer cancel()
+		err = nb.TrainContext(ctx, lfs)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 10.0, nb.Inspect().CasesTotal)
+	})
+
+	t.Run("does not change data on cancelled loading", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(lfs)
+		dump, err := nb.Dump()
+		assert.Nil(t, err)
+
+		nb2 := bayes.New()
+		nb2.Train(lfs[:10])
+		err = nb2.LoadContext(cancelled, dump)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 10.0, nb2.Inspect().CasesTotal)
+
+		err = nb2.LoadContext(context.Background(), dump)
+		assert.Nil(t, err)
+		assertSameModels(t, nb, nb2)
+	})
+}
+
+func assertSameModels(t *testing.T, nb1, nb2 bayes.Bayes) {
+	o1 := nb1.Inspect()
+	o2 := nb2.Inspect()
+	assert.ElementsMatch(t, o1.Classes, o2.Classes)
+	assert.Equal(t, o1.CasesTotal, o2.CasesTotal)
+	assert.Equal(t, o1.ClassCases, o2.ClassCases)
+	assert.Equal(t, o1.FeatureCases, o2.FeatureCases)
+
+	fs := []ft.Feature{
+		{Name: ft.Name("CookieF"), Value: ft.Value("chocolate")},
+		{Name: ft.Name("ShapeF"), Value: ft.Value("star")},
+	}
+	for _, class := range o1.Classes {
+		c := ft.Class(class)
+		odds1, err1 := nb1.PriorOdds(c)
+		odds2, err2 := nb2.PriorOdds(c)
+		assert.Equal(t, err1, err2)
+		assert.Equal(t, odds1, odds2)
+		for _, f := range fs {
+			lh1, err1 := nb1.Likelihood(f, c)
+			lh2, err2 := nb2.Likelihood(f, c)
+			assert.Equal(t, err1, err2)
+			assert.Equal(t, lh1, lh2)
+		}
+	}
+
+	p1, err := nb1.PosteriorOdds(fs)
+	assert.Nil(t, err)
+	p2, err := nb2.PosteriorOdds(fs)
+	assert.Nil(t, err)
+	assert.Equal(t, p1.MaxClass, p2.MaxClass)
+	assert.Equal(t, p1.ClassOdds, p2.ClassOdds)
 }
 
 func TestPriorOdds(t *testing.T) {
@@ -81,7 +408,7 @@ func TestPriorOdds(t *testing.T) {
 		}
 		nb.Train([]ft.ClassFeatures{lf})
 		o := nb.Inspect()
-		assert.Equal(t, 1, o.CasesTotal)
+		assert.Equal(t, 1.0, o.CasesTotal)
 		_, err := nb.PriorOdds(l)
 		assert.Equal(t, "infinite prior odds", err.Error())
 	})
@@ -122,6 +449,51 @@ func TestLikelihood(t *testing.T) {
 	})
 }
 
+func TestOdds(t *testing.T) {
+	nb := bayes.New()
+	nb.Train(threeCookieJarsFeatures())
+	fs := []ft.Feature{
+		{Name: ft.Name("CookieF"), Value: ft.Value("chocolate")},
+		{Name: ft.Name("CookieF"), Value: ft.Value("chocolate")},
+	}
+	p, err := nb.PosteriorOdds(fs)
+	assert.Nil(t, err)
+
+	t.Run("returns odds for a class", func(t *testing.T) {
+		for _, class := range []ft.Class{"Jar1", "Jar2", "Jar3"} {
+			odds, err := nb.Odds(ft.ClassFeatures{Class: class, Features: fs})
+			assert.Nil(t, err)
+			assert.InDelta(t, p.ClassOdds[class], odds, 1e-9)
+		}
+		odds, err := nb.Odds(ft.ClassFeatures{Class: "Jar3", Features: fs})
+		assert.Nil(t, err)
+		assert.InDelta(t, 4.48, odds, 0.001)
+	})
+
+	t.Run("uses options", func(t *testing.T) {
+		lf := ft.ClassFeatures{Class: "Jar3", Features: fs}
+		odds, err := nb.Odds(lf, bayes.OptIgnorePriorOdds(true))
+		assert.Nil(t, err)
+		prior, err := nb.PriorOdds(ft.Class("Jar3"))
+		assert.Nil(t, err)
+		assert.InDelta(t, 4.48/prior, odds, 0.001)
+	})
+
+	t.Run("returns error for unknown class", func(t *testing.T) {
+		_, err := nb.Odds(ft.ClassFeatures{Class: "Jar4", Features: fs})
+		assert.EqualError(t, err, "unknown class 'Jar4'")
+	})
+
+	t.Run("returns error for unknown features", func(t *testing.T) {
+		lf := ft.ClassFeatures{
+			Class:    "Jar1",
+			Features: []ft.Feature{{Name: "UnknownF"}},
+		}
+		_, err := nb.Odds(lf)
+		assert.EqualError(t, err, "all features are unknown")
+	})
+}
+
 func TestPredict2Classes(t *testing.T) {
 	lfs := cookieJarsFeatures()
 	nb := bayes.New()
@@ -195,7 +567,7 @@ func TestPredict2Classes(t *testing.T) {
 			},
 		)
 		assert.Nil(t, err)
-		assert.Equal(t, 3.0, p.MaxOdds)
+		assert.InDelta(t, 3.0, p.MaxOdds, 1e-9)
 		assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
 	})
 
@@ -284,7 +656,7 @@ func TestPredict3Classes(t *testing.T) {
 			panic(err)
 		}
 		assert.Equal(t, ft.Class("Jar3"), p.MaxClass)
-		assert.InDelta(t, 4.479, p.MaxOdds, 0.001)
+		assert.InDelta(t, 4.48, p.MaxOdds, 0.001)
 	})
 
 	t.Run("can calculate for 0 frequency", func(t *testing.T) {
@@ -302,6 +674,269 @@ func TestPredict3Classes(t *testing.T) {
 	})
 }
 
+func TestLogOdds(t *testing.T) {
+	lfs := cookieJarsFeatures()
+	nb := bayes.New()
+	nb.Train(lfs)
+
+	t.Run("provides log odds and log likelihoods", func(t *testing.T) {
+		f := ft.Feature{Name: ft.Name("CookieF"), Value: ft.Value("plain")}
+		p, err := nb.PosteriorOdds([]ft.Feature{f})
+		assert.Nil(t, err)
+		assert.InDelta(t, math.Log(2), p.MaxLogOdds, 1e-9)
+		assert.InDelta(t, math.Log(2), p.LogOdds["Jar1"], 1e-9)
+		assert.InDelta(t, math.Log(1.5), p.LogLikelihoods["Jar1"][f], 1e-9)
+		assert.InDelta(t, 1.5, p.Likelihoods["Jar1"][f], 1e-9)
+	})
+
+	t.Run("does not overflow", func(t *testing.T) {
+		fs := make([]ft.Feature, 2000)
+		for i := range fs {
+			fs[i] = ft.Feature{Name: ft.Name("ShapeF"), Value: ft.Value("star")}
+		}
+		p, err := nb.PosteriorOdds(fs)
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+		assert.True(t, math.IsInf(p.MaxOdds, 1))
+		assert.False(t, math.IsInf(p.MaxLogOdds, 0))
+		assert.Greater(t, p.LogOdds["Jar1"], p.LogOdds["Jar2"])
+	})
+
+	t.Run("does not underflow", func(t *testing.T) {
+		fs := make([]ft.Feature, 2000)
+		for i := range fs {
+			fs[i] = ft.Feature{Name: ft.Name("CookieF"), Value: ft.Value("plain")}
+		}
+		lc := map[ft.Class]int{
+			ft.Class("Jar1"): 1,
+			ft.Class("Jar2"): 1,
+		}
+		p, err := nb.PosteriorOdds(fs, bayes.OptPriorOdds(lc))
+		assert.Nil(t, err)
+		assert.Equal(t, 0.0, p.ClassOdds["Jar2"])
+		assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+		assert.Less(t, p.LogOdds["Jar2"], -500.0)
+	})
+
+	t.Run("skips exponentiation on request", func(t *testing.T) {
+		f := ft.Feature{Name: ft.Name("CookieF"), Value: ft.Value("plain")}
+		p, err := nb.PosteriorOdds([]ft.Feature{f}, bayes.OptLogOnly(true))
+		assert.Nil(t, err)
+		assert.Nil(t, p.ClassOdds)
+		assert.Nil(t, p.Likelihoods)
+		assert.InDelta(t, math.Log(2), p.MaxLogOdds, 1e-9)
+		p.Exp()
+		assert.InDelta(t, 2.0, p.MaxOdds, 1e-9)
+		assert.InDelta(t, 1.5, p.Likelihoods["Jar1"][f], 1e-9)
+	})
+}
+
+func TestClassProbs(t *testing.T) {
+	cookie := []ft.Feature{
+		{Name: ft.Name("CookieF"), Value: ft.Value("chocolate")},
+		{Name: ft.Name("ShapeF"), Value: ft.Value("round")},
+	}
+
+	t.Run("agrees with odds for 2 classes", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		p, err := nb.PosteriorOdds(cookie[:1])
+		assert.Nil(t, err)
+		assert.InDelta(t, 0.4, p.ClassProbs["Jar1"], 1e-9)
+		assert.InDelta(t, 0.6, p.ClassProbs["Jar2"], 1e-9)
+		for class, odds := range p.ClassOdds {
+			assert.InDelta(t, pst.OddsToProb(odds), p.ClassProbs[class], 1e-9)
+		}
+	})
+
+	t.Run("sums up to 1 for 3 classes", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(threeCookieJarsFeatures())
+		p, err := nb.PosteriorOdds(cookie)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(p.ClassProbs))
+		var sum float64
+		for _, v := range p.ClassProbs {
+			sum += v
+		}
+		assert.InDelta(t, 1.0, sum, 1e-9)
+		assert.Greater(t, p.ClassProbs["Jar2"], p.ClassProbs["Jar1"])
+		assert.Greater(t, p.ClassProbs["Jar2"], p.ClassProbs["Jar3"])
+	})
+
+	t.Run("uses new prior odds", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		lc := map[ft.Class]int{
+			ft.Class("Jar1"): 1,
+			ft.Class("Jar2"): 1,
+		}
+		p, err := nb.PosteriorOdds(cookie[:1], bayes.OptPriorOdds(lc))
+		assert.Nil(t, err)
+		assert.InDelta(t, 1.0/3, p.ClassProbs["Jar1"], 1e-9)
+
+		p, err = nb.PosteriorOdds(cookie[:1], bayes.OptIgnorePriorOdds(true))
+		assert.Nil(t, err)
+		assert.InDelta(t, 1.0/3, p.ClassProbs["Jar1"], 1e-9)
+	})
+}
+
+func TestSmoothing(t *testing.T) {
+	plain := ft.Feature{Name: ft.Name("CookieF"), Value: ft.Value("plain")}
+	round := ft.Feature{Name: ft.Name("ShapeF"), Value: ft.Value("round")}
+	jar1 := ft.Class("Jar1")
+
+	tests := []struct {
+		msg          string
+		s            smoothing.Smoothing
+		plain, round float64
+	}{
+		{"crude", smoothing.Crude{}, 1.5, 0.025},
+		{"laplace", smoothing.Laplace{}, 31.0 / 42 * 2, 1.0 / 42 * 32 / 31},
+		{"lidstone", smoothing.Lidstone{Alpha: 0.5},
+			30.5 / 41 * 31 / 15.5, 0.5 / 41 * 31 / 30.5},
+		{"discounting", smoothing.AbsoluteDiscounting{Discount: 0.5},
+			29.5 / 40 * 30 / 14.5, 0.5 / 40 * 30 / 29.5},
+		{"witten-bell", smoothing.WittenBell{},
+			30.0 / 42 * 32 / 15, 1.0 / 41 * 31 / 30},
+	}
+
+	for _, v := range tests {
+		t.Run(v.msg, func(t *testing.T) {
+			nb := bayes.New(bayes.OptSmoothing(v.s))
+			nb.Train(cookieJarsFeatures())
+			lh, err := nb.Likelihood(plain, jar1)
+			assert.Nil(t, err)
+			assert.InDelta(t, v.plain, lh, 1e-9)
+			lh, err = nb.Likelihood(round, jar1)
+			assert.Nil(t, err)
+			assert.InDelta(t, v.round, lh, 1e-9)
+
+			dump, err := nb.Dump()
+			assert.Nil(t, err)
+			nb2 := bayes.New()
+			err = nb2.Load(dump)
+			assert.Nil(t, err)
+			o := nb2.Inspect()
+			assert.Equal(t, v.s.Name(), o.Smoothing)
+			assert.Equal(t, v.s.Param(), o.SmoothingParam)
+			assertSameModels(t, nb, nb2)
+
+			nb3 := bayes.New(bayes.OptSmoothing(v.s))
+			lfs := threeCookieJarsFeatures()
+			nb3.Train(lfs)
+			err = nb3.Untrain(lfs[70:])
+			assert.Nil(t, err)
+			assertSameModels(t, nb, nb3)
+		})
+	}
+
+	t.Run("rejects invalid smoothing", func(t *testing.T) {
+		tests := []struct {
+			s   smoothing.Smoothing
+			err string
+		}{
+			{smoothing.Lidstone{}, "alpha must be positive, got 0"},
+			{smoothing.AbsoluteDiscounting{},
+				"discount must be between 0 and 1, got 0"},
+			{nil, "smoothing is not set"},
+		}
+		for _, v := range tests {
+			nb := bayes.New(bayes.OptSmoothing(v.s))
+			err := nb.Train(cookieJarsFeatures())
+			assert.EqualError(t, err, v.err)
+			_, err = nb.PosteriorOdds([]ft.Feature{plain})
+			assert.EqualError(t, err, v.err)
+		}
+	})
+
+	t.Run("does not load unknown smoothing", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		err := nb.Load([]byte(`{"smoothing": "magic"}`))
+		assert.EqualError(t, err, "unknown smoothing 'magic'")
+		assert.Equal(t, 70.0, nb.Inspect().CasesTotal)
+	})
+}
+
+func TestConcurrency(t *testing.T) {
+	plain := []ft.Feature{{Name: ft.Name("CookieF"), Value: ft.Value("plain")}}
+	lc := map[ft.Class]int{
+		ft.Class("Jar1"): 1,
+		ft.Class("Jar2"): 6,
+	}
+
+	t.Run("classifies with different options", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+
+		var wg sync.WaitGroup
+		for i := range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				switch i % 3 {
+				case 0:
+					p, err := nb.PosteriorOdds(plain)
+					assert.Nil(t, err)
+					assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+					assert.InDelta(t, 2.0, p.MaxOdds, 1e-9)
+				case 1:
+					p, err := nb.PosteriorOdds(plain, bayes.OptPriorOdds(lc))
+					assert.Nil(t, err)
+					assert.Equal(t, ft.Class("Jar2"), p.MaxClass)
+					assert.InDelta(t, 4.0, p.MaxOdds, 1e-9)
+				case 2:
+					p, err := nb.PosteriorOdds(plain, bayes.OptIgnorePriorOdds(true))
+					assert.Nil(t, err)
+					assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+					assert.InDelta(t, 1.5, p.MaxOdds, 1e-9)
+					lh, err := nb.Likelihood(plain[0], ft.Class("Jar1"))
+					assert.Nil(t, err)
+					assert.Equal(t, 1.5, lh)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("classifies during training and loading", func(t *testing.T) {
+		lfs := cookieJarsFeatures()
+		nb := bayes.New()
+		nb.Train(lfs)
+		dump, err := nb.Dump()
+		assert.Nil(t, err)
+
+		var wg sync.WaitGroup
+		for i := range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				switch i % 4 {
+				case 0:
+					assert.Nil(t, nb.Train(lfs))
+				case 1:
+					assert.Nil(t, nb.Load(dump))
+				case 2:
+					_, err := nb.Odds(
+						ft.ClassFeatures{Class: "Jar1", Features: plain},
+						bayes.OptPriorOdds(lc),
+					)
+					assert.Nil(t, err)
+				case 3:
+					p, err := nb.PosteriorOdds(plain)
+					assert.Nil(t, err)
+					assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+					_, err = nb.PriorOdds(ft.Class("Jar2"))
+					assert.Nil(t, err)
+					_ = nb.Inspect()
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
 // cookieJarsFeatures implements features from
 // https://en.wikipedia.org/wiki/Bayesian_inference
 // We change number of cookies in the second jar to 30, so
@@ -0,0 +1,124 @@
+package bayes_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/gnames/bayes/ent/smoothing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBernoulli(t *testing.T) {
+	swims := ft.Feature{Name: "trait", Value: "swims"}
+	absent := ft.Feature{Name: "absentFeatures", Value: "trait"}
+
+	t.Run("uses absent features", func(t *testing.T) {
+		nb := bayes.New(
+			bayes.OptBernoulli("trait"),
+			bayes.OptSmoothing(smoothing.Laplace{}),
+		)
+		err := nb.Train(birdFeatures())
+		assert.Nil(t, err)
+
+		// 'swims' is in 4 of 4 penguins and in 2 of 4 gulls, 'flies' is
+		// absent in 4 of 4 penguins and in 0 of 4 gulls.
+		p, err := nb.PosteriorOdds([]ft.Feature{swims})
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("penguin"), p.MaxClass)
+		llh := p.LogLikelihoods["penguin"]
+		assert.InDelta(t, math.Log(5.0/3), llh[swims], 1e-9)
+		assert.InDelta(t, math.Log(5), llh[absent], 1e-9)
+		assert.InDelta(t, math.Log(25.0/3), p.LogOdds["penguin"], 1e-9)
+
+		lh, err := nb.Likelihood(swims, "penguin")
+		assert.Nil(t, err)
+		assert.InDelta(t, 5.0/3, lh, 1e-9)
+	})
+
+	t.Run("ignores absence without the option", func(t *testing.T) {
+		nb := bayes.New(bayes.OptSmoothing(smoothing.Laplace{}))
+		nb.Train(birdFeatures())
+		p, err := nb.PosteriorOdds([]ft.Feature{swims})
+		assert.Nil(t, err)
+		assert.NotContains(t, p.LogLikelihoods["penguin"], absent)
+	})
+
+	t.Run("works with categorical features", func(t *testing.T) {
+		nb := bayes.New(bayes.OptBernoulli("trait"))
+		nb.Train(birdFeatures())
+		fs := []ft.Feature{{Name: "color", Value: "white"}}
+		p, err := nb.PosteriorOdds(fs)
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("gull"), p.MaxClass)
+
+		// 'white' gives log(4), absent 'flies' gives log(1/4) and absent
+		// 'swims' gives log(2).
+		llh := p.LogLikelihoods["gull"]
+		assert.InDelta(t, math.Log(4), llh[fs[0]], 1e-9)
+		assert.InDelta(t, math.Log(0.5), llh[absent], 1e-9)
+		assert.InDelta(t, math.Log(2), p.LogOdds["gull"], 1e-9)
+	})
+
+	t.Run("counts repeated binary features once", func(t *testing.T) {
+		nb := bayes.New(
+			bayes.OptModel(bayes.Multinomial),
+			bayes.OptBernoulli("trait"),
+		)
+		lfs := birdFeatures()
+		lfs[0].Features = append(lfs[0].Features, swims)
+		nb.Train(lfs)
+		o := nb.Inspect()
+		assert.Equal(t, 4.0, o.FeatureCases["trait"]["swims"]["penguin"])
+
+		fs := []ft.Feature{swims}
+		p, err := nb.PosteriorOdds(append(fs, swims))
+		assert.Nil(t, err)
+		p2, err := nb.PosteriorOdds(fs)
+		assert.Nil(t, err)
+		assert.InDelta(t, p2.LogOdds["penguin"], p.LogOdds["penguin"], 1e-9)
+	})
+
+	t.Run("saves binary features", func(t *testing.T) {
+		nb := bayes.New(bayes.OptBernoulli("trait"))
+		nb.Train(birdFeatures())
+		dump, err := nb.Dump()
+		assert.Nil(t, err)
+		nb2 := bayes.New()
+		err = nb2.Load(dump)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"trait"}, nb2.Inspect().Bernoulli)
+
+		p1, _ := nb.PosteriorOdds([]ft.Feature{swims})
+		p2, err := nb2.PosteriorOdds([]ft.Feature{swims})
+		assert.Nil(t, err)
+		assert.Equal(t, p1.LogOdds, p2.LogOdds)
+	})
+}
+
+// birdFeatures contain traits of birds. Penguins swim and never fly,
+// gulls fly and sometimes swim.
+func birdFeatures() []ft.ClassFeatures {
+	var res []ft.ClassFeatures
+	for range 4 {
+		res = append(res, ft.ClassFeatures{
+			Class: "penguin",
+			Features: []ft.Feature{
+				{Name: "trait", Value: "swims"},
+				{Name: "color", Value: "black"},
+			},
+		})
+	}
+	for i := range 4 {
+		fs := []ft.Feature{
+			{Name: "trait", Value: "flies"},
+			{Name: "color", Value: "white"},
+		}
+		if i%2 == 0 {
+			fs = append(fs, ft.Feature{Name: "trait", Value: "swims"})
+		}
+		res = append(res, ft.ClassFeatures{Class: "gull", Features: fs})
+	}
+	return res
+}
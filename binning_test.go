@@ -0,0 +1,138 @@
+package bayes_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gnames/bayes"
+	"github.com/gnames/bayes/ent/discretize"
+	"github.com/gnames/bayes/ent/distribution"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinning(t *testing.T) {
+	methods := []discretize.Method{
+		discretize.EqualWidth,
+		discretize.EqualFrequency,
+		discretize.MDL,
+	}
+
+	for _, method := range methods {
+		t.Run(string(method), func(t *testing.T) {
+			nb := bayes.New(bayes.OptBinning(method, 4, "SizeF"))
+			err := nb.Train(cookieSizesFeatures())
+			assert.Nil(t, err)
+			o := nb.Inspect()
+			assert.Equal(t, string(method), o.Bins["SizeF"].Method)
+			assert.NotEmpty(t, o.Bins["SizeF"].Boundaries)
+			assert.Contains(t, o.FeatureCases, "SizeF")
+
+			p, err := nb.PosteriorOdds(sizeFeatures("3"))
+			assert.Nil(t, err)
+			assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+			p, err = nb.PosteriorOdds(sizeFeatures("9"))
+			assert.Nil(t, err)
+			assert.Equal(t, ft.Class("Jar2"), p.MaxClass)
+
+			lh, err := nb.Likelihood(sizeFeatures("3")[0], ft.Class("Jar1"))
+			assert.Nil(t, err)
+			assert.Greater(t, lh, 1.0)
+
+			dump, err := nb.Dump()
+			assert.Nil(t, err)
+			nb2 := bayes.New()
+			err = nb2.Load(dump)
+			assert.Nil(t, err)
+			p2, err := nb2.PosteriorOdds(sizeFeatures("9"))
+			assert.Nil(t, err)
+			assert.Equal(t, p.MaxClass, p2.MaxClass)
+			assert.InDelta(t, p.MaxLogOdds, p2.MaxLogOdds, 1e-9)
+		})
+	}
+
+	t.Run("mdl separates classes", func(t *testing.T) {
+		nb := bayes.New(bayes.OptBinning(discretize.MDL, 0, "SizeF"))
+		nb.Train(cookieSizesFeatures())
+		o := nb.Inspect()
+		assert.Equal(t, []float64{5.5}, o.Bins["SizeF"].Boundaries)
+		assert.Equal(t, 40.0, o.FeatureCases["SizeF"]["[-Inf,5.5)"]["Jar1"])
+		assert.Equal(t, 30.0, o.FeatureCases["SizeF"]["[5.5,+Inf)"]["Jar2"])
+
+		p, err := nb.PosteriorOdds(sizeFeatures("5"))
+		assert.Nil(t, err)
+		bin := ft.Feature{Name: "SizeF", Value: "[-Inf,5.5)"}
+		assert.Contains(t, p.LogLikelihoods["Jar1"], bin)
+	})
+
+	t.Run("keeps learned boundaries", func(t *testing.T) {
+		lfs := cookieSizesFeatures()
+		nb := bayes.New(bayes.OptBinning(discretize.EqualWidth, 2, "SizeF"))
+		nb.Train(lfs[:40])
+		bounds := nb.Inspect().Bins["SizeF"].Boundaries
+		assert.Equal(t, []float64{3}, bounds)
+
+		nb.Train(lfs[40:])
+		assert.Equal(t, bounds, nb.Inspect().Bins["SizeF"].Boundaries)
+		err := nb.Untrain(lfs[40:])
+		assert.Nil(t, err)
+		assert.Equal(t, bounds, nb.Inspect().Bins["SizeF"].Boundaries)
+	})
+
+	t.Run("replaces numeric distributions", func(t *testing.T) {
+		nb := bayes.New(
+			bayes.OptNumeric(distribution.Gaussian, "SizeF"),
+			bayes.OptBinning(discretize.MDL, 0, "SizeF"),
+		)
+		nb.Train(cookieSizesFeatures())
+		o := nb.Inspect()
+		assert.NotContains(t, o.Numeric, "SizeF")
+		assert.Contains(t, o.Bins, "SizeF")
+	})
+
+	t.Run("checks values", func(t *testing.T) {
+		nb := bayes.New(bayes.OptBinning(discretize.MDL, 0, "SizeF"))
+		lfs := cookieSizesFeatures()
+		lfs[3].Features[0].Value = "big"
+		err := nb.Train(lfs)
+		assert.ErrorContains(t, err,
+			"invalid value 'big' of numeric feature 'SizeF'")
+		assert.Nil(t, nb.Inspect().Bins["SizeF"].Boundaries)
+
+		nb.Train(cookieSizesFeatures())
+		_, err = nb.PosteriorOdds(sizeFeatures("small"))
+		assert.ErrorContains(t, err,
+			"invalid value 'small' of numeric feature 'SizeF'")
+	})
+
+	t.Run("rejects too few bins", func(t *testing.T) {
+		methods := []discretize.Method{
+			discretize.EqualWidth, discretize.EqualFrequency,
+		}
+		for _, method := range methods {
+			nb := bayes.New(bayes.OptBinning(method, 1, "SizeF"))
+			msg := fmt.Sprintf("cannot bin feature 'SizeF': "+
+				"%s method needs at least 2 bins, got 1", method)
+			err := nb.Train(cookieSizesFeatures())
+			assert.EqualError(t, err, msg)
+			_, err = nb.PosteriorOdds(sizeFeatures("3"))
+			assert.EqualError(t, err, msg)
+		}
+	})
+
+	t.Run("does not load wrong bins", func(t *testing.T) {
+		nb := bayes.New()
+		err := nb.Load([]byte(`{"bins": {"SizeF": {"method": "random"}}}`))
+		assert.EqualError(t, err, "unknown discretization method 'random'")
+		err = nb.Load([]byte(
+			`{"bins": {"SizeF": {"method": "mdl", "boundaries": [3, 1]}}}`,
+		))
+		assert.EqualError(t, err,
+			"boundaries of bins of feature 'SizeF' are not sorted")
+		err = nb.Load([]byte(
+			`{"bins": {"SizeF": {"method": "equal-width", "bins": 0}}}`,
+		))
+		assert.EqualError(t, err, "cannot bin feature 'SizeF': "+
+			"equal-width method needs at least 2 bins, got 0")
+	})
+}
@@ -3,140 +3,256 @@ package bayes
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 
+	"github.com/gnames/bayes/ent/calibration"
 	ft "github.com/gnames/bayes/ent/feature"
 	pst "github.com/gnames/bayes/ent/posterior"
+	"github.com/gnames/bayes/ent/smoothing"
 )
 
-type Option func(nb *bayes)
-
-// OptPriorOdds allows dynamical change of prior odds used in calculations.
-// Sometimes prior odds during classification event are very different from
-// ones aquired during training. If for example 'real' prior odds are 100 times
-// larger it means the calculated posterior odds will be 100 times smaller than
-// what they would suppose to be.
-func OptPriorOdds(lc map[ft.Class]int) Option {
-	return func(nb *bayes) {
-		nb.tmpClassCases = lc
-		for _, v := range lc {
-			nb.tmpCasesTotal += v
-		}
-	}
-}
-
-// OptIgnorePriorOdds might be needed if it is a muV
-// PriorOdds already are accounted for.
-func OptIgnorePriorOdds(b bool) Option {
-	return func(nb *bayes) {
-		nb.ignorePriorOdds = b
-	}
-}
-
 // PosteriorOdds is a general function that runs NaiveBayes classifier against
 // trained set. It can take a different PriorOdds value to influence
-// calculation of the Posterior Odds.
+// calculation of the Posterior Odds. It is safe to call PosteriorOdds
+// concurrently, options of one call do not affect other calls.
 func (nb *bayes) PosteriorOdds(
 	fs []ft.Feature,
 	opts ...Option,
 ) (pst.Odds, error) {
-	nb.tmpClassCases = nil
-	nb.tmpCasesTotal = 0
-	nb.ignorePriorOdds = false
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
 
-	lc := nb.classCases
-	ct := nb.casesTotal
+	return nb.posteriorOdds(fs, nb.newConfig(opts))
+}
 
-	for _, opt := range opts {
-		opt(nb)
+func (nb *bayes) posteriorOdds(fs []ft.Feature, cfg config) (pst.Odds, error) {
+	if cfg.err != nil {
+		return pst.Odds{}, cfg.err
 	}
-
-	if nb.tmpClassCases != nil {
-		lc = nb.tmpClassCases
-		ct = nb.tmpCasesTotal
+	if err := nb.providePriors(fs, &cfg); err != nil {
+		return pst.Odds{}, err
 	}
-
-	l := len(lc)
+	l := len(cfg.classCases)
 	if l < 2 {
 		return pst.Odds{}, errors.New("classes are empty")
 	}
-	return nb.multiPosterior(fs, lc, ct)
-}
-
-func (nb *bayes) noSuchFeature(f ft.Feature) bool {
-	if _, ok := nb.featureCases[f]; ok {
-		return false
-	}
-	return true
+	return nb.multiPosterior(fs, cfg)
 }
 
 func (nb *bayes) multiPosterior(
 	features []ft.Feature,
-	classCases map[ft.Class]int,
-	casesTotal int,
+	cfg config,
 ) (pst.Odds, error) {
+	classCases := cfg.classCases
+	casesTotal := cfg.casesTotal
 	var maxClass ft.Class
-	var maxOdds float64
+	maxLogOdds := math.Inf(-1)
 	var res pst.Odds
-	oddsPost := make(map[ft.Class]float64)
-	likelihoods := make(pst.Likelihoods)
+	logOddsPost := make(map[ft.Class]float64)
+	logLikelihoods := make(pst.Likelihoods)
+
+	features, err := nb.binFeatures(features)
+	if err != nil {
+		return res, err
+	}
+	features = nb.uniqueFeatures(features)
+	var known int
+	var unknown []ft.Feature
+	for _, f := range features {
+		if kind, ok := nb.numeric[f.Name]; ok {
+			if _, err := parseNumeric(kind, f); err != nil {
+				return res, err
+			}
+		}
+		if nb.knownFeature(f) {
+			known++
+		} else if !slices.Contains(unknown, f) {
+			unknown = append(unknown, f)
+		}
+	}
+	if cfg.unknown == ErrorUnknown && len(unknown) > 0 {
+		return res, unknownError(unknown)
+	}
+	absent := nb.absentFeatures(features)
+	absentNames := slices.Sorted(maps.Keys(absent))
 
 	for _, class := range nb.classes {
 		odds, err := odds(class, classCases, casesTotal)
 		if err != nil {
 			return res, fmt.Errorf("cannot calculate odds: %s", err.Error())
 		}
-		oddsPost[class] = 1
-		if !nb.ignorePriorOdds {
-			oddsPost[class] = odds
-		}
-		likelihoods[class] = make(map[ft.Feature]float64)
-		if !nb.ignorePriorOdds {
+		logLikelihoods[class] = make(map[ft.Feature]float64)
+		if !cfg.ignorePriorOdds && nb.model != Complement {
+			logOddsPost[class] = math.Log(odds)
 			po := ft.Feature{Name: "priorOdds", Value: "true"}
-			likelihoods[class][po] = odds
+			logLikelihoods[class][po] = math.Log(odds)
 		}
 
 		var i int
 		for _, f := range features {
 			// features are missing if training data did not have
 			// their value.
-			if nb.noSuchFeature(f) {
-				continue
+			if !nb.knownFeature(f) {
+				if cfg.unknown == SkipUnknown || cfg.unknown == ErrorUnknown {
+					continue
+				}
+				i++
+				if cfg.unknown == NeutralUnknown || !nb.backoff(f) {
+					continue
+				}
+			} else {
+				i++
 			}
 
-			lh, err := nb.Likelihood(f, class)
-			if err != nil {
-				return res, err
+			logFeature, logRest, ok := nb.logProbs(f, class)
+			if !ok {
+				continue
+			}
+			llh := logFeature - logRest
+			if nb.model == Complement && nb.complementFeature(f) {
+				llh = nb.complementWeight(logRest, class)
 			}
-			likelihoods[class][f] = lh
+			logLikelihoods[class][f] += llh
 
+			logOddsPost[class] += llh
+		}
+
+		// absence of values of binary features is evidence as well.
+		for _, name := range absentNames {
 			i++
-			oddsPost[class] *= lh
+			var llh float64
+			for _, f := range absent[name] {
+				pFeature, pRest := nb.absenceProbs(f, class)
+				llh += math.Log(pFeature) - math.Log(pRest)
+			}
+			af := ft.Feature{Name: "absentFeatures", Value: ft.Value(name)}
+			logLikelihoods[class][af] = llh
+			logOddsPost[class] += llh
 		}
 
 		if i == 0 {
 			return res, errors.New("all features are unknown")
 		}
 
-		if oddsPost[class] > maxOdds {
-			maxOdds = oddsPost[class]
+		if maxClass == "" || logOddsPost[class] > maxLogOdds {
+			maxLogOdds = logOddsPost[class]
 			maxClass = class
 		}
 	}
 	p := pst.Odds{
-		ClassOdds:   oddsPost,
-		MaxClass:    maxClass,
-		MaxOdds:     maxOdds,
-		ClassCases:  classCases,
-		Likelihoods: likelihoods,
+		MaxClass:   maxClass,
+		ClassCases: maps.Clone(cfg.counts),
+		Unknown:    unknown,
+		Coverage:   1,
+	}
+	// Complement model calculates scores instead of log odds.
+	if nb.model == Complement {
+		p.Scores = logOddsPost
+	} else {
+		p.LogOdds = logOddsPost
+		p.MaxLogOdds = maxLogOdds
+		p.LogLikelihoods = logLikelihoods
+		p.ClassProbs = classProbs(nb.classes, logOddsPost)
+		if !cfg.ignorePriorOdds {
+			p.PriorProbs = make(map[ft.Class]float64, len(nb.classes))
+			for _, class := range nb.classes {
+				p.PriorProbs[class] = classCases[class] / casesTotal
+			}
+		}
+	}
+	if nb.calibration != nil && !cfg.uncalibrated {
+		p.ClassProbs = nb.calibration.Apply(nb.classes, p.ClassProbs)
+	}
+	if len(features) > 0 {
+		p.Coverage = float64(known) / float64(len(features))
+	}
+	if len(nb.parents) > 0 {
+		p.NodeProbs = nb.nodeProbs(p.ClassProbs)
+		p.Path = nb.topDown(p.NodeProbs, cfg.pathThreshold)
+	}
+	p.Rank(cfg.topK)
+	if cfg.costs != nil {
+		p.MinCost(cfg.costs)
+	}
+	p.AbstainReason = abstainReason(p, known, cfg)
+	p.Abstained = p.AbstainReason != ""
+	if !cfg.logOnly && nb.model != Complement {
+		p.Exp()
 	}
 	return p, nil
 }
 
+// classProbs converts one-vs-rest log odds of classes to probabilities
+// and normalizes them, so probabilities follow the order of odds. The
+// normalization is done in log space to keep precision for extreme odds.
+func classProbs(
+	classes []ft.Class,
+	logOdds map[ft.Class]float64,
+) map[ft.Class]float64 {
+	logs := make(map[ft.Class]float64, len(logOdds))
+	for class, v := range logOdds {
+		// the logarithm of the probability is log(1/(1+exp(-v))).
+		if v >= 0 {
+			logs[class] = -math.Log1p(math.Exp(-v))
+		} else {
+			logs[class] = v - math.Log1p(math.Exp(v))
+		}
+	}
+	return calibration.Softmax(classes, logs)
+}
+
+// uniqueFeatures removes repeated values of binary features, because
+// training counts them once per feature set as well.
+func (nb *bayes) uniqueFeatures(fs []ft.Feature) []ft.Feature {
+	if len(nb.bernoulli) == 0 {
+		return fs
+	}
+	res := make([]ft.Feature, 0, len(fs))
+	seen := make(map[ft.Feature]struct{}, len(fs))
+	for _, f := range fs {
+		if _, ok := nb.bernoulli[f.Name]; ok {
+			if _, ok := seen[f]; ok {
+				continue
+			}
+			seen[f] = struct{}{}
+		}
+		res = append(res, f)
+	}
+	return res
+}
+
+// abstainReason returns the reason to abstain from the decision, or an
+// empty string if the evidence is strong enough.
+func abstainReason(p pst.Odds, known int, cfg config) pst.Reason {
+	if known < cfg.minKnownFeatures {
+		return pst.FewFeatures
+	}
+	if cfg.minPosterior > 0 && p.ClassProbs[p.MaxClass] < cfg.minPosterior {
+		return pst.LowPosterior
+	}
+	if cfg.minMargin > 0 && p.Margin < cfg.minMargin {
+		return pst.LowMargin
+	}
+	return ""
+}
+
+// Likelihood returns the ratio between probability of a feature for a class
+// and probability of the feature for all other classes.
 func (nb *bayes) Likelihood(
 	feature ft.Feature,
 	class ft.Class,
 ) (float64, error) {
-	err := nb.checkFeature(feature)
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
+
+	fs, err := nb.binFeatures([]ft.Feature{feature})
+	if err != nil {
+		return 0, err
+	}
+	feature = fs[0]
+	err = nb.checkFeature(feature)
 	if err != nil {
 		return 0, err
 	}
@@ -144,23 +260,144 @@ func (nb *bayes) Likelihood(
 	if err != nil {
 		return 0, err
 	}
-	smooth := 1
 
+	if _, ok := nb.numeric[feature.Name]; ok {
+		logFeature, logRest, ok := nb.logProbs(feature, class)
+		if !ok {
+			return 0, fmt.Errorf(
+				"not enough data for numeric feature '%s' and class '%s'",
+				feature.Name, class,
+			)
+		}
+		return math.Exp(logFeature - logRest), nil
+	}
+	pFeature, pRest := nb.featureProbs(feature, class)
+	return pFeature / pRest, nil
+}
+
+// logProbs returns logarithms of probabilities of a known feature for a
+// class and for the rest of the classes. For numeric features they are
+// logarithms of probability densities. If there are no values of a numeric
+// feature for the class or for the rest of the classes, the last returned
+// value is false.
+func (nb *bayes) logProbs(
+	feature ft.Feature,
+	class ft.Class,
+) (float64, float64, bool) {
+	kind, ok := nb.numeric[feature.Name]
+	if !ok {
+		pFeature, pRest := nb.featureProbs(feature, class)
+		return math.Log(pFeature), math.Log(pRest), true
+	}
+
+	x, err := parseNumeric(kind, feature)
+	if err != nil {
+		return 0, 0, false
+	}
+	stats, ok := nb.numStats[feature.Name][class]
+	if !ok {
+		return 0, 0, false
+	}
+	stats = stats.Smooth(nb.numTotal[feature.Name])
+	rest := nb.numRest[feature.Name][class]
+	if rest.Count <= epsilon {
+		return 0, 0, false
+	}
+	return stats.LogPDF(kind, x), rest.LogPDF(kind, x), true
+}
+
+// featureProbs returns probability of a feature for a class and probability
+// of the feature for the rest of the classes.
+func (nb *bayes) featureProbs(
+	feature ft.Feature,
+	class ft.Class,
+) (float64, float64) {
 	countFeature := nb.featureCases[feature][class]
+	countRest := nb.featureTotal[feature] - countFeature
+	if countRest <= epsilon {
+		countRest = 0
+	}
 
-	countRest := (nb.featureTotal[feature] - countFeature)
+	name := feature.Name
+	if _, ok := nb.bernoulli[name]; ok {
+		total := nb.classCases[class]
+		totalRest := nb.casesTotal - total
+		pFeature := nb.smoothing.Prob(binaryCounts(countFeature, total))
+		pRest := nb.smoothing.Prob(binaryCounts(countRest, totalRest))
+		return pFeature, pRest
+	}
 
-	// crude smoothing to prevent fails for very unlikely cases.
-	if countFeature == 0 {
-		countFeature = smooth
+	total := nb.classCases[class]
+	totalRest := nb.casesTotal - total
+	if nb.model == Multinomial || nb.model == Complement {
+		total = nb.vocab.tokens[name][class]
+		totalRest = nb.vocab.tokensTotal[name] - total
 	}
+	pFeature := nb.smoothing.Prob(smoothing.Counts{
+		Count:  countFeature,
+		Total:  total,
+		Values: nb.vocab.values[name],
+		Seen:   nb.vocab.seen[name][class],
+	})
+	pRest := nb.smoothing.Prob(smoothing.Counts{
+		Count:  countRest,
+		Total:  totalRest,
+		Values: nb.vocab.values[name],
+		Seen:   nb.vocab.seenRest(name, class),
+	})
+	return pFeature, pRest
+}
+
+// absenceProbs returns probability of absence of a binary feature for a
+// class and for the rest of the classes.
+func (nb *bayes) absenceProbs(
+	feature ft.Feature,
+	class ft.Class,
+) (float64, float64) {
+	countFeature := nb.featureCases[feature][class]
+	countRest := nb.featureTotal[feature] - countFeature
+	total := nb.classCases[class]
+	totalRest := nb.casesTotal - total
 
-	if countRest == 0 {
-		countRest = smooth
+	absent := max(total-countFeature, 0)
+	absentRest := max(totalRest-countRest, 0)
+	if absentRest <= epsilon {
+		absentRest = 0
 	}
-	// end crude smoothing
+	pFeature := nb.smoothing.Prob(binaryCounts(absent, total))
+	pRest := nb.smoothing.Prob(binaryCounts(absentRest, totalRest))
+	return pFeature, pRest
+}
 
-	pFeature := float64(countFeature) / float64(nb.classCases[class])
-	pRest := float64(countRest) / float64(nb.casesTotal-nb.classCases[class])
-	return pFeature / pRest, nil
+// binaryCounts returns counts of one of two outcomes of a binary feature.
+func binaryCounts(count, total float64) smoothing.Counts {
+	seen := 1
+	if count > epsilon && total-count > epsilon {
+		seen = 2
+	}
+	return smoothing.Counts{Count: count, Total: total, Values: 2, Seen: seen}
+}
+
+// absentFeatures returns known values of binary features that are absent
+// from the features. Values are sorted, so results are reproducible.
+func (nb *bayes) absentFeatures(
+	features []ft.Feature,
+) map[ft.Name][]ft.Feature {
+	if len(nb.bernoulli) == 0 {
+		return nil
+	}
+	present := make(map[ft.Feature]struct{}, len(features))
+	for _, f := range features {
+		present[f] = struct{}{}
+	}
+	res := make(map[ft.Name][]ft.Feature, len(nb.bernoulli))
+	for name := range nb.bernoulli {
+		for _, v := range slices.Sorted(maps.Keys(nb.vocab.known[name])) {
+			f := ft.Feature{Name: name, Value: v}
+			if _, ok := present[f]; !ok {
+				res[name] = append(res[name], f)
+			}
+		}
+	}
+	return res
 }
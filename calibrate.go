@@ -0,0 +1,53 @@
+package bayes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gnames/bayes/ent/calibration"
+	ft "github.com/gnames/bayes/ent/feature"
+)
+
+// Calibrate fits a calibration of probabilities of classes to a labeled
+// validation set, which should not be a part of the training data. The
+// options are used for classification of the validation set. After that
+// ClassProbs of classification results are calibrated, while odds stay
+// unchanged. The calibration is saved in dumps. It does not follow
+// changes of training data, so it should be fitted again after training.
+func (nb *bayes) Calibrate(
+	m calibration.Method,
+	lfs []ft.ClassFeatures,
+	opts ...Option,
+) error {
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+
+	if nb.model == Complement {
+		return errors.New("cannot calibrate: " +
+			"complement model does not calculate probabilities")
+	}
+	cfg := nb.newConfig(opts)
+	cfg.uncalibrated = true
+	ss := make([]calibration.Sample, len(lfs))
+	for i, lf := range lfs {
+		if err := nb.checkClass(lf.Class); err != nil {
+			return fmt.Errorf("cannot calibrate: %w", err)
+		}
+		p, err := nb.posteriorOdds(lf.Features, cfg)
+		if err != nil {
+			return fmt.Errorf("cannot classify feature set %d: %w", i, err)
+		}
+		ss[i] = calibration.Sample{
+			Probs:  p.ClassProbs,
+			Class:  lf.Class,
+			Weight: lf.CaseWeight(),
+		}
+	}
+
+	c, err := calibration.Fit(m, ss)
+	if err != nil {
+		return fmt.Errorf("cannot calibrate: %w", err)
+	}
+	nb.calibration = &c
+	return nil
+}
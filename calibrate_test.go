@@ -0,0 +1,85 @@
+package bayes_test
+
+import (
+	"testing"
+
+	"github.com/gnames/bayes"
+	"github.com/gnames/bayes/ent/calibration"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalibrate(t *testing.T) {
+	plain := []ft.Feature{{Name: "CookieF", Value: "plain"}}
+	chocolate := []ft.Feature{{Name: "CookieF", Value: "chocolate"}}
+	// in the validation set kinds of cookies do not tell jars apart.
+	validation := []ft.ClassFeatures{
+		{Class: "Jar1", Features: plain},
+		{Class: "Jar2", Features: plain},
+		{Class: "Jar1", Features: chocolate},
+		{Class: "Jar2", Features: chocolate},
+	}
+
+	t.Run("calibrates probabilities", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		p, err := nb.PosteriorOdds(plain)
+		assert.Nil(t, err)
+		assert.InDelta(t, 2.0/3, p.ClassProbs["Jar1"], 1e-9)
+
+		err = nb.Calibrate(calibration.Isotonic, validation)
+		assert.Nil(t, err)
+		p2, err := nb.PosteriorOdds(plain)
+		assert.Nil(t, err)
+		assert.InDelta(t, 0.5, p2.ClassProbs["Jar1"], 1e-9)
+		assert.Equal(t, p.LogOdds, p2.LogOdds)
+
+		p2, err = nb.PosteriorOdds(plain, bayes.OptUncalibrated(true))
+		assert.Nil(t, err)
+		assert.InDelta(t, 2.0/3, p2.ClassProbs["Jar1"], 1e-9)
+
+		err = nb.Calibrate(calibration.Temperature, validation)
+		assert.Nil(t, err)
+		p2, err = nb.PosteriorOdds(plain)
+		assert.Nil(t, err)
+		assert.InDelta(t, 0.5, p2.ClassProbs["Jar1"], 0.01)
+		assert.Greater(t, p2.ClassProbs["Jar1"], 0.5)
+	})
+
+	t.Run("saves calibration in dumps", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		nb.Calibrate(calibration.Platt, validation)
+		p, _ := nb.PosteriorOdds(plain)
+		dump, err := nb.Dump()
+		assert.Nil(t, err)
+		assert.Equal(t, calibration.Platt, nb.Inspect().Calibration.Method)
+
+		nb2 := bayes.New()
+		err = nb2.Load(dump)
+		assert.Nil(t, err)
+		p2, err := nb2.PosteriorOdds(plain)
+		assert.Nil(t, err)
+		assert.InDelta(t, p.ClassProbs["Jar1"], p2.ClassProbs["Jar1"], 1e-9)
+
+		err = nb2.Load([]byte(`{"calibration": {"method": "magic"}}`))
+		assert.EqualError(t, err, "unknown calibration method 'magic'")
+	})
+
+	t.Run("checks validation set", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		err := nb.Calibrate(calibration.Platt, []ft.ClassFeatures{
+			{Class: "Jar5", Features: plain},
+		})
+		assert.EqualError(t, err,
+			"cannot calibrate: there is no label 'Jar5'")
+
+		err = nb.Calibrate(calibration.Platt, nil)
+		assert.EqualError(t, err,
+			"cannot calibrate: no samples for calibration")
+		p, err := nb.PosteriorOdds(plain)
+		assert.Nil(t, err)
+		assert.InDelta(t, 2.0/3, p.ClassProbs["Jar1"], 1e-9)
+	})
+}
@@ -0,0 +1,70 @@
+package bayes
+
+import (
+	"errors"
+	"math"
+
+	ft "github.com/gnames/bayes/ent/feature"
+)
+
+// updateComplement recalculates normalization factors of weights of
+// features for Complement model. A weight of a feature for a class is the
+// logarithm of the feature's probability in the rest of the classes, and
+// the factor is the sum of absolute values of all weights of the class.
+func (nb *bayes) updateComplement() {
+	if nb.model != Complement {
+		return
+	}
+	res := make(map[ft.Class]float64, len(nb.classes))
+	for f := range nb.featureCases {
+		if !nb.complementFeature(f) {
+			continue
+		}
+		for _, class := range nb.classes {
+			_, pRest := nb.featureProbs(f, class)
+			res[class] += math.Abs(math.Log(pRest))
+		}
+	}
+	nb.complement = res
+}
+
+// complementFeature returns true if Complement model uses normalized
+// weights for the feature. Numeric and binary features use their usual
+// likelihoods.
+func (nb *bayes) complementFeature(f ft.Feature) bool {
+	if _, ok := nb.numeric[f.Name]; ok {
+		return false
+	}
+	_, ok := nb.bernoulli[f.Name]
+	return !ok
+}
+
+// complementWeight returns the weight of a feature for a class in
+// Complement model. The weight is larger when the feature is rare in the
+// rest of the classes.
+func (nb *bayes) complementWeight(logRest float64, class ft.Class) float64 {
+	norm := nb.complement[class]
+	if norm <= 0 {
+		norm = 1
+	}
+	return -logRest / norm
+}
+
+// checkComplement returns an error if a classification with Complement
+// model needs prior or posterior probabilities, which the model does not
+// calculate.
+func (nb *bayes) checkComplement(cfg config) error {
+	if nb.model != Complement {
+		return nil
+	}
+	if cfg.priorCounts != nil || cfg.priorProbs != nil ||
+		cfg.priorProfile != "" || cfg.priorKey != "" || nb.priors != nil {
+		return errors.New("complement model does not use prior probabilities")
+	}
+	if cfg.minPosterior > 0 || cfg.costs != nil || len(nb.parents) > 0 {
+		return errors.New(
+			"complement model does not calculate posterior probabilities",
+		)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package bayes_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gnames/bayes"
+	"github.com/gnames/bayes/ent/calibration"
+	ft "github.com/gnames/bayes/ent/feature"
+	pst "github.com/gnames/bayes/ent/posterior"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplement(t *testing.T) {
+	t.Run("uses normalized complement weights", func(t *testing.T) {
+		nb := bayes.New(bayes.OptModel(bayes.Complement))
+		err := nb.Train(textFeatures())
+		assert.Nil(t, err)
+
+		p, err := nb.PosteriorOdds(words("buy"))
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("spam"), p.MaxClass)
+
+		// In 5 words of ham there are no 'buy' (smoothed to 1), 'cheap'
+		// and 'lunch', 2 'now' and 2 'meet'.
+		norm := 3*math.Log(5) + 2*math.Log(5.0/2)
+		assert.InDelta(t, math.Log(5)/norm, p.Scores["spam"], 1e-9)
+		assert.Equal(t, ft.Class("spam"), p.Ranking[0].Class)
+		assert.InDelta(t, p.Scores["spam"], p.Ranking[0].Score, 1e-9)
+		assert.InDelta(t, p.Scores["spam"]-p.Scores["ham"], p.Margin, 1e-9)
+
+		// scores are not odds or probabilities.
+		assert.Nil(t, p.LogOdds)
+		assert.Nil(t, p.ClassOdds)
+		assert.Nil(t, p.ClassProbs)
+		assert.Equal(t, 0.0, p.MaxOdds)
+	})
+
+	t.Run("rejects options that need probabilities", func(t *testing.T) {
+		nb := bayes.New(bayes.OptModel(bayes.Complement))
+		lfs := textFeatures()
+		nb.Train(lfs)
+		fs := words("now", "meet")
+		priors := map[ft.Class]int{"spam": 100, "ham": 1}
+		_, err := nb.PosteriorOdds(fs, bayes.OptPriorOdds(priors))
+		assert.EqualError(t, err,
+			"complement model does not use prior probabilities")
+
+		msg := "complement model does not calculate posterior probabilities"
+		_, err = nb.PosteriorOdds(fs, bayes.OptMinPosterior(0.9))
+		assert.EqualError(t, err, msg)
+		_, err = nb.PosteriorOdds(fs, bayes.OptCosts(pst.Costs{}))
+		assert.EqualError(t, err, msg)
+
+		_, err = nb.Odds(ft.ClassFeatures{Class: "ham", Features: fs})
+		assert.EqualError(t, err, "complement model does not calculate odds")
+		err = nb.Calibrate(calibration.Platt, lfs)
+		assert.EqualError(t, err, "cannot calibrate: "+
+			"complement model does not calculate probabilities")
+
+		p, err := nb.PosteriorOdds(fs, bayes.OptIgnorePriorOdds(true))
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("ham"), p.MaxClass)
+	})
+
+	t.Run("keeps weights after untrain and load", func(t *testing.T) {
+		lfs := textFeatures()
+		nb := bayes.New(bayes.OptModel(bayes.Complement))
+		nb.Train(lfs)
+		nb.Untrain(lfs[:1])
+		nb2 := bayes.New(bayes.OptModel(bayes.Complement))
+		nb2.Train(lfs[1:])
+		p1, err := nb.PosteriorOdds(words("now"))
+		assert.Nil(t, err)
+		p2, err := nb2.PosteriorOdds(words("now"))
+		assert.Nil(t, err)
+		assert.InDelta(t, p2.Scores["ham"], p1.Scores["ham"], 1e-9)
+
+		dump, err := nb.Dump()
+		assert.Nil(t, err)
+		nb3 := bayes.New()
+		err = nb3.Load(dump)
+		assert.Nil(t, err)
+		assert.Equal(t, "complement", nb3.Inspect().Model)
+		p3, err := nb3.PosteriorOdds(words("now"))
+		assert.Nil(t, err)
+		assert.InDelta(t, p1.Scores["ham"], p3.Scores["ham"], 1e-9)
+	})
+}
@@ -0,0 +1,29 @@
+package bayes
+
+import (
+	"fmt"
+	"math"
+
+	pst "github.com/gnames/bayes/ent/posterior"
+)
+
+// checkCosts makes sure that costs of misclassification refer to known
+// classes and are non-negative numbers.
+func (nb *bayes) checkCosts(costs pst.Costs) error {
+	for actual, row := range costs {
+		if err := nb.checkClass(actual); err != nil {
+			return fmt.Errorf("invalid costs: %w", err)
+		}
+		for decided, v := range row {
+			if err := nb.checkClass(decided); err != nil {
+				return fmt.Errorf("invalid costs: %w", err)
+			}
+			if v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+				return fmt.Errorf("cost of deciding '%s' for class '%s' "+
+					"must be a non-negative number, got %g",
+					decided, actual, v)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package bayes_test
+
+import (
+	"testing"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	pst "github.com/gnames/bayes/ent/posterior"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCosts(t *testing.T) {
+	nb := bayes.New()
+	nb.Train(cookieJarsFeatures())
+	plain := []ft.Feature{{Name: "CookieF", Value: "plain"}}
+
+	t.Run("does not calculate costs by default", func(t *testing.T) {
+		p, err := nb.PosteriorOdds(plain)
+		assert.Nil(t, err)
+		assert.Empty(t, p.MinCostClass)
+		assert.Nil(t, p.ExpectedCosts)
+	})
+
+	t.Run("decides by minimal expected cost", func(t *testing.T) {
+		// probability of Jar1 is 2/3, of Jar2 is 1/3.
+		costs := pst.Costs{"Jar2": {"Jar1": 5}}
+		p, err := nb.PosteriorOdds(plain, bayes.OptCosts(costs))
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+		assert.Equal(t, ft.Class("Jar2"), p.MinCostClass)
+		assert.InDelta(t, 5.0/3, p.ExpectedCosts["Jar1"], 1e-9)
+		assert.InDelta(t, 2.0/3, p.ExpectedCosts["Jar2"], 1e-9)
+
+		res := nb.PosteriorOddsBatch(
+			[][]ft.Feature{plain}, bayes.OptCosts(costs),
+		)
+		assert.Nil(t, res[0].Err)
+		assert.Equal(t, ft.Class("Jar2"), res[0].Odds.MinCostClass)
+	})
+
+	t.Run("checks costs", func(t *testing.T) {
+		costs := pst.Costs{"Jar2": {"Jar5": 5}}
+		_, err := nb.PosteriorOdds(plain, bayes.OptCosts(costs))
+		assert.EqualError(t, err, "invalid costs: there is no label 'Jar5'")
+
+		costs = pst.Costs{"Jar2": {"Jar1": -1}}
+		_, err = nb.PosteriorOdds(plain, bayes.OptCosts(costs))
+		assert.EqualError(t, err, "cost of deciding 'Jar1' for class 'Jar2' "+
+			"must be a non-negative number, got -1")
+	})
+}
@@ -53,6 +53,11 @@ apply it "naively" state that their evidences are completely independent from
 each other. In practice Naive Bayes approach often shows good results in spite
 of this known fallacy.
 
+Results of classification also contain probabilities of classes that sum up
+to 1. They are calculated from odds of classes and normalized, so the class
+with the best odds is the most probable one. For two classes they are the
+same as probabilities from odds.
+
 Training and prior odds
 
 It is quite possible that while likelihoods of evidences are representative for
@@ -62,6 +67,131 @@ hypothesis much better in Antarctica because odds to meet a penguin there are
 much higher than in the northern hemisphere. Therefore we give an ability to
 supply prior probability value at a classification event.
 
+Smoothing
+
+Training data rarely contains all possible combinations of features and
+classes. A feature that never occurred with a class would make the
+posterior odds of the class zero. Smoothing algorithms estimate
+probabilities of features so that such features get small but non-zero
+probabilities. The algorithm is selected with OptSmoothing during creation
+of a Bayes object, and it is saved in the dump of the training data.
+
+Numeric features
+
+Features are usually categorical, their values are strings like 'star' or
+'round'. Measurements like lengths or counts can be declared as numeric
+features with OptNumeric. In this case every class learns a distribution
+(Gaussian, log-normal, Poisson or kernel density estimation) of the
+feature's values, and likelihoods are calculated from densities of these
+distributions.
+
+Alternatively numeric features can be converted to categorical ones with
+OptBinning. Boundaries of bins are learned from the first training batch
+with the feature by equal-width, equal-frequency or supervised MDL method,
+and numbers are replaced by labels of their bins like '[2.5,4)' during
+training and classification.
+
+Models
+
+By default a Bayes object uses the Categorical model, where probabilities
+of features are estimated from numbers of feature sets of a class, so
+feature sets should not repeat features. For text-like data the Multinomial
+model can be selected with OptModel. In this model repeated features of a
+feature set are term frequencies, and probabilities of features are
+calculated from the number of all occurrences of features with the same
+name in a class.
+
+For classes with very different numbers of cases the Complement model is
+available. It estimates probabilities of features from all classes except
+the given one and normalizes weights of features, so large classes do not
+dominate the results. This model calculates Scores of classes instead of
+odds and probabilities, so options that need prior or posterior
+probabilities return an error.
+
+Features with some names can be declared as binary with OptBernoulli. Every
+known value of such a feature is either present in a feature set or absent
+from it, and absence of a value that is common for a class is evidence
+against the class. Binary features can be combined with other features in
+any model.
+
+Prior odds
+
+Prior odds of classes are calculated from training data, but they can be
+changed for a classification call. OptPriorOdds sets numbers of cases of
+classes, OptPriorProbs and OptPriorClassOdds set probabilities or odds of
+some classes, while the rest of classes share the remaining probability
+according to their training data. Priors are validated before the
+classification, and priors that give zero or full probability to a class
+are smoothed. Results keep numbers of cases in ClassCases and report the
+prior probabilities that were used in PriorProbs.
+
+Priors can also depend on the context of a classification, for example a
+penguin is a likely guess in Antarctica and an unlikely one in Europe. A
+prior.Provider set by OptPriorProvider returns probabilities of classes
+from features and from a key given by OptPriorKey, such as a region. The
+prior package contains providers that look up priors by keys or by values
+of a feature, and a provider that falls back to the training data.
+Priors set by options of a call take precedence over the provider.
+
+Frequencies of classes that are used often, for example per source of
+data, can be saved in the model as named prior profiles by
+AddPriorProfile. Profiles are saved in dumps together with the training
+data, and OptPriorProfile selects a profile for a classification call.
+
+Unknown features
+
+Features that are not known from training are skipped by default. Other
+policies set by OptUnknown return an error for any unknown feature, treat
+unknown features as neutral evidence, or estimate their probabilities by
+the smoothing algorithm. Results always contain the list of unknown
+features and the ratio of known features to all of them.
+
+Abstaining from decisions
+
+Classification always finds the best class, even if the evidence is weak.
+Options OptMinPosterior, OptMinMargin and OptMinKnownFeatures set limits
+for the probability of the best class, the difference between the best and
+the second best classes and the number of known features. If a limit is
+not reached, the result is marked as Abstained with the reason, so such
+cases can be reviewed by people.
+
+Costs of decisions
+
+Some mistakes cost more than others, for example missing a scientific
+name can be worse than taking noise for a name. OptCosts sets costs of
+misclassification for pairs of classes. Then results contain expected
+costs of deciding for every class, calculated from probabilities of
+classes, and the class with the minimal expected cost.
+
+Calibration
+
+Naive Bayes assumes that features are independent, so its probabilities
+of classes are often too close to 0 or 1. Calibrate fits a correction of
+probabilities to a labeled validation set that is not a part of the
+training data. The calibration package provides Platt scaling, isotonic
+regression and temperature scaling. The calibration is saved in dumps and
+is applied to probabilities of classes of every classification result,
+unless OptUncalibrated is used. Odds of classes are not calibrated. The
+calibration should be fitted again after changes of the training data.
+
+Hierarchy of classes
+
+Classes can form a hierarchy, for example a taxonomy of families, genera
+and species, declared by OptParents. Then results of classification
+contain probabilities of all classes of the hierarchy, where a class gets
+probabilities of all its descendants. The result also contains a path
+from the top of the hierarchy that goes to the most probable child on
+every level and stops at the deepest class that passes the threshold set
+by OptPathThreshold.
+
+Multi-label classification
+
+Feature sets that belong to several classes at once are handled by a
+MultiLabel classifier created by NewMultiLabel. Such classes are called
+labels, and every label gets its own model that decides if the label is
+present or absent. The result contains odds of every label and labels with
+probabilities that pass the threshold set by OptThreshold.
+
 Terminology
 
 In natural language processing `evidences` are often called `features`. We
@@ -2,10 +2,18 @@ package bayes
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
 
 	"github.com/gnames/bayes/ent/bayesdump"
+	"github.com/gnames/bayes/ent/calibration"
+	"github.com/gnames/bayes/ent/discretize"
+	"github.com/gnames/bayes/ent/distribution"
 	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/gnames/bayes/ent/smoothing"
 )
 
 // Dump serializes a Bayes object into a JSON format.
@@ -15,9 +23,21 @@ func (nb *bayes) Dump() ([]byte, error) {
 
 // Load deserializes a JSON text into Bayes object. The function needs
 // to know how to convert a string that represents a class to an object.
+// Load replaces all existing training data with the data from the dump.
 func (nb *bayes) Load(dump []byte) error {
+	return nb.LoadContext(context.Background(), dump)
+}
+
+// LoadContext is the same as Load, but it stops if the context is
+// cancelled. In such case the training data stay unchanged and the
+// context's error is returned.
+func (nb *bayes) LoadContext(ctx context.Context, dump []byte) error {
+	var res bayesdump.BayesDump
 	r := bytes.NewReader(dump)
-	return json.NewDecoder(r).Decode(nb)
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		return err
+	}
+	return nb.loadDump(ctx, res)
 }
 
 // MarshalJSON serializes a NaiveBayes object to JSON.
@@ -27,35 +47,105 @@ func (nb *bayes) MarshalJSON() ([]byte, error) {
 }
 
 func (nb *bayes) Inspect() bayesdump.BayesDump {
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
+
 	ls := make([]string, len(nb.classes))
 	for i, v := range nb.classes {
 		ls[i] = string(v)
 	}
 
-	lfs := make(map[string]int)
+	lfs := make(map[string]float64)
 	for k, v := range nb.classCases {
 		lfs[string(k)] = v
 	}
 
-	ffs := make(map[string]map[string]map[string]int)
+	ffs := make(map[string]map[string]map[string]float64)
 	for fk, fv := range nb.featureCases {
 		if _, ok := ffs[string(fk.Name)]; !ok {
-			val1 := make(map[string]map[string]int)
+			val1 := make(map[string]map[string]float64)
 			ffs[string(fk.Name)] = val1
 		}
 		if _, ok := ffs[string(fk.Name)][string(fk.Value)]; !ok {
-			val2 := make(map[string]int)
+			val2 := make(map[string]float64)
 			ffs[string(fk.Name)][string(fk.Value)] = val2
 		}
 		for lk, v := range fv {
 			ffs[string(fk.Name)][string(fk.Value)][string(lk)] = v
 		}
 	}
+	var nfs map[string]bayesdump.Numeric
+	if len(nb.numeric) > 0 {
+		nfs = make(map[string]bayesdump.Numeric, len(nb.numeric))
+	}
+	for name, kind := range nb.numeric {
+		n := bayesdump.Numeric{Distribution: string(kind)}
+		if len(nb.numStats[name]) > 0 {
+			n.ClassStats = make(map[string]distribution.Stats)
+		}
+		for class, st := range nb.numStats[name] {
+			n.ClassStats[string(class)] = st
+		}
+		nfs[string(name)] = n
+	}
+	var bfs map[string]bayesdump.Bins
+	if len(nb.binning) > 0 {
+		bfs = make(map[string]bayesdump.Bins, len(nb.binning))
+	}
+	for name, b := range nb.binning {
+		bfs[string(name)] = bayesdump.Bins{
+			Method:     string(b.Method),
+			Bins:       b.Bins,
+			Boundaries: nb.boundaries[name],
+		}
+	}
+
+	var bernoulli []string
+	for name := range nb.bernoulli {
+		bernoulli = append(bernoulli, string(name))
+	}
+	slices.Sort(bernoulli)
+
+	var parents map[string]string
+	if len(nb.parents) > 0 {
+		parents = make(map[string]string, len(nb.parents))
+	}
+	for class, parent := range nb.parents {
+		parents[string(class)] = string(parent)
+	}
+
+	var profiles map[string]map[string]float64
+	if len(nb.profiles) > 0 {
+		profiles = make(map[string]map[string]float64, len(nb.profiles))
+	}
+	for name, counts := range nb.profiles {
+		profiles[name] = make(map[string]float64, len(counts))
+		for class, v := range counts {
+			profiles[name][string(class)] = v
+		}
+	}
+
+	var calib *calibration.Calibrator
+	if nb.calibration != nil {
+		c := *nb.calibration
+		c.X, c.Y = slices.Clone(c.X), slices.Clone(c.Y)
+		calib = &c
+	}
+
 	return bayesdump.BayesDump{
-		Classes:      ls,
-		CasesTotal:   nb.casesTotal,
-		ClassCases:   lfs,
-		FeatureCases: ffs,
+		Classes:        ls,
+		CasesTotal:     nb.casesTotal,
+		ClassCases:     lfs,
+		FeatureCases:   ffs,
+		Model:          string(nb.model),
+		Smoothing:      nb.smoothing.Name(),
+		SmoothingParam: nb.smoothing.Param(),
+		Numeric:        nfs,
+		Bernoulli:      bernoulli,
+		Parents:        parents,
+		Bins:           bfs,
+		PriorProfiles:  profiles,
+		Calibration:    calib,
 	}
 }
 
@@ -65,31 +155,146 @@ func (nb *bayes) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &res); err != nil {
 		return err
 	}
+	return nb.loadDump(context.Background(), res)
+}
+
+// loadDump replaces training data with the data from a dump. The data
+// are prepared first and replaced at once, so if the context is cancelled,
+// the training data stay unchanged.
+func (nb *bayes) loadDump(ctx context.Context, res bayesdump.BayesDump) error {
+	smooth, err := smoothing.New(res.Smoothing, res.SmoothingParam)
+	if err != nil {
+		return err
+	}
 
-	nb.classes = make([]ft.Class, len(res.Classes))
+	model, err := NewModel(res.Model)
+	if err != nil {
+		return err
+	}
+
+	tmp := &bayes{
+		model:     model,
+		smoothing: smooth,
+		numeric:   make(map[ft.Name]distribution.Kind),
+		binning:   make(map[ft.Name]discretize.Binning),
+		bernoulli: make(map[ft.Name]struct{}),
+		parents:   make(map[ft.Class]ft.Class),
+		profiles:  make(map[string]map[ft.Class]float64),
+	}
+	tmp.reset()
+
+	for class, parent := range res.Parents {
+		tmp.parents[ft.Class(class)] = ft.Class(parent)
+	}
+	if err = checkParents(tmp.parents); err != nil {
+		return err
+	}
+
+	for _, name := range res.Bernoulli {
+		tmp.bernoulli[ft.Name(name)] = struct{}{}
+	}
+
+	for k, v := range res.Bins {
+		name := ft.Name(k)
+		method, err := discretize.NewMethod(v.Method)
+		if err != nil {
+			return err
+		}
+		b := discretize.Binning{Method: method, Bins: v.Bins}
+		if err = b.Check(); err != nil {
+			return fmt.Errorf("cannot bin feature '%s': %w", k, err)
+		}
+		tmp.binning[name] = b
+		if v.Boundaries == nil {
+			continue
+		}
+		if !slices.IsSorted(v.Boundaries) {
+			return fmt.Errorf("boundaries of bins of feature '%s' are not sorted", k)
+		}
+		tmp.boundaries[name] = v.Boundaries
+	}
+
+	for k, v := range res.Numeric {
+		name := ft.Name(k)
+		kind, err := distribution.NewKind(v.Distribution)
+		if err != nil {
+			return err
+		}
+		tmp.numeric[name] = kind
+		if len(v.ClassStats) == 0 {
+			continue
+		}
+		tmp.numStats[name] = make(map[ft.Class]distribution.Stats)
+		for class, st := range v.ClassStats {
+			tmp.numStats[name][ft.Class(class)] = st
+		}
+	}
+	if res.Calibration != nil {
+		if model == Complement {
+			return errors.New("complement model does not use calibration")
+		}
+		if err = res.Calibration.Check(); err != nil {
+			return err
+		}
+		tmp.calibration = res.Calibration
+	}
+
+	for name, counts := range res.PriorProfiles {
+		profile := make(map[ft.Class]float64, len(counts))
+		for class, v := range counts {
+			profile[ft.Class(class)] = v
+		}
+		if len(profile) == 0 {
+			return fmt.Errorf(
+				"prior profile '%s' has no numbers of cases", name,
+			)
+		}
+		if err = checkPriorCounts(profile); err != nil {
+			return fmt.Errorf("cannot load prior profile '%s': %w", name, err)
+		}
+		tmp.profiles[name] = profile
+	}
+
+	tmp.classes = make([]ft.Class, len(res.Classes))
 	for i, v := range res.Classes {
-		nb.classes[i] = ft.Class(v)
+		tmp.classes[i] = ft.Class(v)
 	}
 
-	nb.casesTotal = res.CasesTotal
+	tmp.casesTotal = res.CasesTotal
 
 	for k, v := range res.ClassCases {
-		nb.classCases[ft.Class(k)] = v
+		tmp.classCases[ft.Class(k)] = v
 	}
 
 	for k1, v1 := range res.FeatureCases {
 		name := ft.Name(k1)
 		for k2, v2 := range v1 {
-			v := make(map[ft.Class]int)
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			v := make(map[ft.Class]float64)
 			f := ft.Feature{Name: name, Value: ft.Value(k2)}
-			nb.featureCases[f] = v
+			tmp.featureCases[f] = v
 			for k3, v3 := range v2 {
 				class := ft.Class(k3)
-				nb.featureCases[f][class] = v3
+				tmp.featureCases[f][class] = v3
 			}
 		}
 	}
 
-	nb.featTotal()
+	tmp.featTotal()
+	for f := range tmp.featureCases {
+		tmp.vocabAdd(f)
+	}
+	tmp.updateNumRest()
+	tmp.updateComplement()
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+
+	nb.replace(tmp)
 	return nil
 }
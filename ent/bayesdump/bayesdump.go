@@ -1,5 +1,10 @@
 package bayesdump
 
+import (
+	"github.com/gnames/bayes/ent/calibration"
+	"github.com/gnames/bayes/ent/distribution"
+)
+
 // BayesDump is a printing/serializing friendly presentation of data from
 // private fields of Bayes implementation.
 // It contains everything needed for training the classifier.
@@ -9,13 +14,84 @@ type BayesDump struct {
 	Classes []string `json:"classes"`
 
 	// CasesTotal is the number of entities collected during training.
-	CasesTotal int `json:"casesTotal"`
+	// Numbers of entities are weighted, so they do not have to be integers.
+	CasesTotal float64 `json:"casesTotal"`
 
 	// ClassCases is the number of entities partitioned to their corresponding
 	// classes during training.
-	ClassCases map[string]int `json:"classCases"`
+	ClassCases map[string]float64 `json:"classCases"`
 
 	// FeatureCases is the entities from training paritioned by separate
 	// features.
-	FeatureCases map[string]map[string]map[string]int `json:"featureCases"`
+	FeatureCases map[string]map[string]map[string]float64 `json:"featureCases"`
+
+	// Model is the kind of Naive Bayes model. Empty model means the
+	// categorical model.
+	Model string `json:"model,omitempty"`
+
+	// Smoothing is the name of the algorithm used for estimation of
+	// probabilities of features. Empty name means the crude smoothing.
+	Smoothing string `json:"smoothing,omitempty"`
+
+	// SmoothingParam is a parameter of the smoothing algorithm, for example
+	// alpha of the Lidstone smoothing.
+	SmoothingParam float64 `json:"smoothingParam,omitempty"`
+
+	// Numeric contains numeric features and their distributions.
+	Numeric map[string]Numeric `json:"numeric,omitempty"`
+
+	// Bernoulli are names of binary features. Absent values of such
+	// features are used as evidence.
+	Bernoulli []string `json:"bernoulli,omitempty"`
+
+	// Parents are parents of classes in a hierarchy of classes.
+	Parents map[string]string `json:"parents,omitempty"`
+
+	// Bins contains numeric features that are converted to categorical
+	// ones by bins. Values of such features in FeatureCases are labels of
+	// the bins.
+	Bins map[string]Bins `json:"bins,omitempty"`
+
+	// PriorProfiles are named numbers of cases of classes, for example
+	// real-world frequencies of classes for a region or a source of data.
+	PriorProfiles map[string]map[string]float64 `json:"priorProfiles,omitempty"`
+
+	// Calibration contains parameters of calibration of probabilities of
+	// classes. It is nil if probabilities are not calibrated.
+	Calibration *calibration.Calibrator `json:"calibration,omitempty"`
+}
+
+// Numeric contains distributions of a numeric feature.
+type Numeric struct {
+	// Distribution is the kind of the distributions.
+	Distribution string `json:"distribution"`
+
+	// ClassStats are statistics of the feature's values for every class.
+	ClassStats map[string]distribution.Stats `json:"classStats,omitempty"`
+}
+
+// Bins contains settings and boundaries of bins of a numeric feature.
+type Bins struct {
+	// Method is the algorithm that learns boundaries of bins.
+	Method string `json:"method"`
+
+	// Bins is the requested number of bins.
+	Bins int `json:"bins,omitempty"`
+
+	// Boundaries are learned boundaries between bins. They are null if
+	// bins are not learned yet.
+	Boundaries []float64 `json:"boundaries"`
+}
+
+// MultiLabelDump contains training data of a multi-label classifier.
+type MultiLabelDump struct {
+	// Labels are all known labels.
+	Labels []string `json:"labels"`
+
+	// All contains all training data as one class.
+	All BayesDump `json:"all"`
+
+	// Models contain training data of presence and absence models of
+	// every label.
+	Models map[string]BayesDump `json:"models"`
 }
@@ -0,0 +1,420 @@
+// package calibration corrects probabilities of classes calculated by Naive
+// Bayes. Because of the assumption of independent features such
+// probabilities are often too close to 0 or 1. Calibrators are fitted to
+// probabilities calculated for a labeled validation set.
+package calibration
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	ft "github.com/gnames/bayes/ent/feature"
+)
+
+// minProb limits probabilities, so their logarithms stay finite.
+const minProb = 1e-15
+
+// Method is an algorithm of calibration.
+type Method string
+
+const (
+	// Platt fits a logistic function to logarithms of odds of classes.
+	Platt Method = "platt"
+
+	// Isotonic fits a non-decreasing step function to probabilities of
+	// classes. It needs more data than the other methods, but it does not
+	// assume any shape of the correction.
+	Isotonic Method = "isotonic"
+
+	// Temperature divides logarithms of probabilities by a temperature.
+	// It keeps the order of classes and changes only their confidence.
+	Temperature Method = "temperature"
+)
+
+// NewMethod converts a string to a Method.
+func NewMethod(s string) (Method, error) {
+	m := Method(s)
+	switch m {
+	case Platt, Isotonic, Temperature:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown calibration method '%s'", s)
+	}
+}
+
+// Sample is a classified entity from the validation set.
+type Sample struct {
+	// Probs are not calibrated probabilities of classes.
+	Probs map[ft.Class]float64
+
+	// Class is the real class of the entity.
+	Class ft.Class
+
+	// Weight is the number of cases the sample represents. Samples with
+	// zero weight are ignored.
+	Weight float64
+}
+
+// Calibrator contains fitted parameters of a calibration method.
+type Calibrator struct {
+	// Method is the algorithm of calibration.
+	Method Method `json:"method"`
+
+	// A is the slope of the logistic function of the Platt method.
+	A float64 `json:"a,omitempty"`
+
+	// B is the intercept of the logistic function of the Platt method.
+	B float64 `json:"b,omitempty"`
+
+	// Temperature is the divisor of logarithms of probabilities of the
+	// Temperature method.
+	Temperature float64 `json:"temperature,omitempty"`
+
+	// X are sorted probabilities of steps of the Isotonic method.
+	X []float64 `json:"x,omitempty"`
+
+	// Y are calibrated probabilities of steps of the Isotonic method.
+	Y []float64 `json:"y,omitempty"`
+}
+
+// Fit creates a calibrator from the validation samples.
+func Fit(m Method, ss []Sample) (Calibrator, error) {
+	var weighted []Sample
+	for i, s := range ss {
+		w := s.Weight
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return Calibrator{},
+				fmt.Errorf("invalid weight %g of sample %d", w, i)
+		}
+		if w > 0 {
+			weighted = append(weighted, s)
+		}
+	}
+	if len(weighted) == 0 {
+		return Calibrator{}, errors.New("no samples for calibration")
+	}
+	ss = weighted
+	res := Calibrator{Method: m}
+	switch m {
+	case Platt:
+		res.A, res.B = fitPlatt(points(ss))
+	case Isotonic:
+		res.X, res.Y = fitIsotonic(points(ss))
+	case Temperature:
+		res.Temperature = fitTemperature(ss)
+	default:
+		return res, fmt.Errorf("unknown calibration method '%s'", m)
+	}
+	return res, nil
+}
+
+// Check makes sure that parameters of the calibrator are valid, for
+// example after they are loaded from a dump.
+func (c Calibrator) Check() error {
+	if _, err := NewMethod(string(c.Method)); err != nil {
+		return err
+	}
+	switch c.Method {
+	case Temperature:
+		if !(c.Temperature > 0) || math.IsInf(c.Temperature, 0) {
+			return fmt.Errorf("temperature must be positive, got %g",
+				c.Temperature)
+		}
+	case Isotonic:
+		if len(c.X) == 0 || len(c.X) != len(c.Y) {
+			return errors.New("steps of isotonic calibration do not match")
+		}
+		if !slices.IsSorted(c.X) || !slices.IsSorted(c.Y) {
+			return errors.New("steps of isotonic calibration are not sorted")
+		}
+	}
+	return nil
+}
+
+// Apply returns calibrated probabilities of classes. They are normalized
+// to sum up to 1. Classes define the order of summation, which keeps
+// results reproducible.
+func (c Calibrator) Apply(
+	classes []ft.Class,
+	probs map[ft.Class]float64,
+) map[ft.Class]float64 {
+	res := make(map[ft.Class]float64, len(probs))
+	if c.Method == Temperature {
+		logs := make(map[ft.Class]float64, len(probs))
+		for class, p := range probs {
+			logs[class] = math.Log(max(p, minProb)) / c.Temperature
+		}
+		return Softmax(classes, logs)
+	}
+
+	var sum float64
+	for _, class := range classes {
+		p, ok := probs[class]
+		if !ok {
+			continue
+		}
+		switch c.Method {
+		case Platt:
+			res[class] = sigmoid(c.A*logit(p) + c.B)
+		case Isotonic:
+			res[class] = interpolate(c.X, c.Y, p)
+		}
+		sum += res[class]
+	}
+	if sum <= 0 {
+		return probs
+	}
+	for class := range res {
+		res[class] /= sum
+	}
+	return res
+}
+
+// point is a probability of a class for a sample, and whether the sample
+// belongs to the class.
+type point struct {
+	x, w float64
+	y    bool
+}
+
+// points converts samples to probabilities of every class of every
+// sample. They are sorted by the probability.
+func points(ss []Sample) []point {
+	var res []point
+	for _, s := range ss {
+		for class, p := range s.Probs {
+			res = append(res, point{x: p, w: s.Weight, y: class == s.Class})
+		}
+	}
+	slices.SortStableFunc(res, func(a, b point) int {
+		return cmp.Compare(a.x, b.x)
+	})
+	return res
+}
+
+// fitPlatt finds parameters of the logistic function by Newton's method
+// with backtracking line search. Targets are smoothed as suggested by
+// Platt, which prevents overfitting.
+func fitPlatt(ps []point) (float64, float64) {
+	var pos, neg float64
+	for _, p := range ps {
+		if p.y {
+			pos += p.w
+		} else {
+			neg += p.w
+		}
+	}
+	hi := (pos + 1) / (pos + 2)
+	lo := 1 / (neg + 2)
+	target := func(p point) float64 {
+		if p.y {
+			return hi
+		}
+		return lo
+	}
+	// nll is the negative log likelihood of the targets.
+	nll := func(a, b float64) float64 {
+		var res float64
+		for _, p := range ps {
+			z := a*logit(p.x) + b
+			t := target(p)
+			// log(1+exp(z)) - t*z in a stable form.
+			softplus := max(z, 0) + math.Log1p(math.Exp(-math.Abs(z)))
+			res += p.w * (softplus - t*z)
+		}
+		return res
+	}
+
+	a, b := 1.0, 0.0
+	f := nll(a, b)
+	for range 100 {
+		var ga, gb, haa, hab, hbb float64
+		for _, p := range ps {
+			x := logit(p.x)
+			q := sigmoid(a*x + b)
+			d := p.w * (q - target(p))
+			s := p.w * max(q*(1-q), 1e-12)
+			ga += d * x
+			gb += d
+			haa += s * x * x
+			hab += s * x
+			hbb += s
+		}
+		// small ridge keeps the hessian invertible.
+		haa += 1e-9
+		hbb += 1e-9
+		det := haa*hbb - hab*hab
+		if det <= 0 {
+			break
+		}
+		da := (hbb*ga - hab*gb) / det
+		db := (haa*gb - hab*ga) / det
+
+		step := 1.0
+		for ; step > 1e-10; step /= 2 {
+			na, nb := a-step*da, b-step*db
+			if nf := nll(na, nb); nf <= f {
+				a, b, f = na, nb, nf
+				break
+			}
+		}
+		change := math.Abs(step*da) + math.Abs(step*db)
+		if step <= 1e-10 || change < 1e-10 {
+			break
+		}
+	}
+	return a, b
+}
+
+// fitIsotonic fits a non-decreasing step function by the pool adjacent
+// violators algorithm. Every step is represented by its mean probability
+// and its share of positive points.
+func fitIsotonic(ps []point) ([]float64, []float64) {
+	type block struct {
+		x, y, w float64
+	}
+	merge := func(a, b block) block {
+		w := a.w + b.w
+		return block{
+			x: (a.x*a.w + b.x*b.w) / w,
+			y: (a.y*a.w + b.y*b.w) / w,
+			w: w,
+		}
+	}
+
+	// points with the same probability make one block.
+	var groups []block
+	for _, p := range ps {
+		var y float64
+		if p.y {
+			y = 1
+		}
+		b := block{x: p.x, y: y, w: p.w}
+		if l := len(groups) - 1; l >= 0 && groups[l].x == b.x {
+			groups[l] = merge(groups[l], b)
+			continue
+		}
+		groups = append(groups, b)
+	}
+
+	var bs []block
+	for _, b := range groups {
+		for len(bs) > 0 && bs[len(bs)-1].y >= b.y {
+			b = merge(bs[len(bs)-1], b)
+			bs = bs[:len(bs)-1]
+		}
+		bs = append(bs, b)
+	}
+
+	xs := make([]float64, len(bs))
+	ys := make([]float64, len(bs))
+	for i, b := range bs {
+		xs[i], ys[i] = b.x, b.y
+	}
+	return xs, ys
+}
+
+// interpolate returns a value of the step function at x. Values between
+// steps are interpolated linearly, values outside of steps are the ones
+// of the closest step.
+func interpolate(xs, ys []float64, x float64) float64 {
+	i, _ := slices.BinarySearch(xs, x)
+	switch {
+	case i == 0:
+		return ys[0]
+	case i == len(xs):
+		return ys[len(ys)-1]
+	case xs[i] == x:
+		return ys[i]
+	}
+	r := (x - xs[i-1]) / (xs[i] - xs[i-1])
+	return ys[i-1] + r*(ys[i]-ys[i-1])
+}
+
+// fitTemperature finds the temperature that minimizes the negative log
+// likelihood of real classes by the golden section search over the
+// logarithm of the temperature.
+func fitTemperature(ss []Sample) float64 {
+	nll := func(logT float64) float64 {
+		t := math.Exp(logT)
+		var res float64
+		for _, s := range ss {
+			maxLog := math.Inf(-1)
+			for _, p := range s.Probs {
+				maxLog = max(maxLog, math.Log(max(p, minProb))/t)
+			}
+			var sum float64
+			for _, p := range s.Probs {
+				sum += math.Exp(math.Log(max(p, minProb))/t - maxLog)
+			}
+			logY := math.Log(max(s.Probs[s.Class], minProb))/t - maxLog
+			res -= s.Weight * (logY - math.Log(sum))
+		}
+		return res
+	}
+
+	g := (math.Sqrt(5) - 1) / 2
+	lo, hi := -5.0, 5.0
+	a, b := hi-g*(hi-lo), lo+g*(hi-lo)
+	fa, fb := nll(a), nll(b)
+	for hi-lo > 1e-6 {
+		if fa < fb {
+			hi, b, fb = b, a, fa
+			a = hi - g*(hi-lo)
+			fa = nll(a)
+		} else {
+			lo, a, fa = a, b, fb
+			b = lo + g*(hi-lo)
+			fb = nll(b)
+		}
+	}
+	return math.Exp((lo + hi) / 2)
+}
+
+func logit(p float64) float64 {
+	p = min(max(p, minProb), 1-minProb)
+	return math.Log(p / (1 - p))
+}
+
+func sigmoid(x float64) float64 {
+	if x >= 0 {
+		return 1 / (1 + math.Exp(-x))
+	}
+	e := math.Exp(x)
+	return e / (1 + e)
+}
+
+// Softmax converts logarithms of not normalized probabilities into
+// probabilities that sum up to 1. Classes define the order of summation,
+// which keeps results reproducible. Classes without logarithms are
+// skipped. If the maximal logarithm is infinite, the result is empty.
+func Softmax(
+	classes []ft.Class,
+	logs map[ft.Class]float64,
+) map[ft.Class]float64 {
+	res := make(map[ft.Class]float64, len(logs))
+	maxLog := math.Inf(-1)
+	for _, class := range classes {
+		if v, ok := logs[class]; ok {
+			maxLog = max(maxLog, v)
+		}
+	}
+	if math.IsInf(maxLog, 0) {
+		return res
+	}
+
+	var sum float64
+	for _, class := range classes {
+		if v, ok := logs[class]; ok {
+			res[class] = math.Exp(v - maxLog)
+			sum += res[class]
+		}
+	}
+	for class := range res {
+		res[class] /= sum
+	}
+	return res
+}
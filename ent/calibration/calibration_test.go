@@ -0,0 +1,110 @@
+package calibration_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gnames/bayes/ent/calibration"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMethod(t *testing.T) {
+	m, err := calibration.NewMethod("isotonic")
+	assert.Nil(t, err)
+	assert.Equal(t, calibration.Isotonic, m)
+	_, err = calibration.NewMethod("magic")
+	assert.EqualError(t, err, "unknown calibration method 'magic'")
+}
+
+func TestSoftmax(t *testing.T) {
+	classes := []ft.Class{"a", "b", "c"}
+	logs := map[ft.Class]float64{"a": math.Log(3), "b": 0}
+	res := calibration.Softmax(classes, logs)
+	assert.Equal(t, 2, len(res))
+	assert.InDelta(t, 0.75, res["a"], 1e-12)
+	assert.InDelta(t, 0.25, res["b"], 1e-12)
+
+	logs["a"] = math.Inf(1)
+	assert.Empty(t, calibration.Softmax(classes, logs))
+}
+
+func TestFit(t *testing.T) {
+	classes := []ft.Class{"a", "b"}
+	sure := map[ft.Class]float64{"a": 0.99, "b": 0.01}
+	// the model is sure about 'a', but it is right only in 70% of cases.
+	ss := overconfident()
+
+	methods := []calibration.Method{
+		calibration.Platt,
+		calibration.Isotonic,
+		calibration.Temperature,
+	}
+	for _, m := range methods {
+		t.Run(string(m), func(t *testing.T) {
+			c, err := calibration.Fit(m, ss)
+			assert.Nil(t, err)
+			assert.Nil(t, c.Check())
+			res := c.Apply(classes, sure)
+			assert.InDelta(t, 0.7, res["a"], 0.01)
+			assert.InDelta(t, 1.0, res["a"]+res["b"], 1e-9)
+		})
+	}
+
+	t.Run("weights samples", func(t *testing.T) {
+		ws := []calibration.Sample{
+			{Probs: sure, Class: "a", Weight: 7},
+			{Probs: sure, Class: "b", Weight: 3},
+			{Probs: sure, Class: "b", Weight: 0},
+		}
+		c, err := calibration.Fit(calibration.Isotonic, ws)
+		assert.Nil(t, err)
+		assert.InDelta(t, 0.7, c.Apply(classes, sure)["a"], 1e-9)
+
+		_, err = calibration.Fit(calibration.Isotonic, ws[2:])
+		assert.EqualError(t, err, "no samples for calibration")
+		ws[2].Weight = -1
+		_, err = calibration.Fit(calibration.Isotonic, ws)
+		assert.EqualError(t, err, "invalid weight -1 of sample 2")
+	})
+
+	t.Run("checks calibrators", func(t *testing.T) {
+		_, err := calibration.Fit(calibration.Platt, nil)
+		assert.EqualError(t, err, "no samples for calibration")
+		_, err = calibration.Fit("magic", ss)
+		assert.EqualError(t, err, "unknown calibration method 'magic'")
+
+		c := calibration.Calibrator{Method: calibration.Temperature}
+		assert.EqualError(t, c.Check(), "temperature must be positive, got 0")
+		c = calibration.Calibrator{
+			Method: calibration.Isotonic,
+			X:      []float64{0.5, 0.1},
+			Y:      []float64{0.1, 0.5},
+		}
+		assert.EqualError(t, c.Check(),
+			"steps of isotonic calibration are not sorted")
+	})
+}
+
+func overconfident() []calibration.Sample {
+	var res []calibration.Sample
+	for i := range 100 {
+		var a, b ft.Class = "a", "b"
+		if i%10 < 3 {
+			a, b = b, a
+		}
+		res = append(res,
+			calibration.Sample{
+				Probs:  map[ft.Class]float64{"a": 0.99, "b": 0.01},
+				Class:  a,
+				Weight: 1,
+			},
+			calibration.Sample{
+				Probs:  map[ft.Class]float64{"a": 0.01, "b": 0.99},
+				Class:  b,
+				Weight: 1,
+			},
+		)
+	}
+	return res
+}
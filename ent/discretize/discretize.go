@@ -0,0 +1,306 @@
+// package discretize learns boundaries of bins for numeric values. Bins
+// allow to convert numeric values to categorical ones.
+package discretize
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	ft "github.com/gnames/bayes/ent/feature"
+)
+
+// Method is an algorithm that learns boundaries of bins.
+type Method string
+
+const (
+	// EqualWidth splits the range of values into bins of the same width.
+	EqualWidth Method = "equal-width"
+
+	// EqualFrequency splits values into bins with the same number of
+	// values in each bin.
+	EqualFrequency Method = "equal-frequency"
+
+	// MDL is the supervised method of Fayyad and Irani. It chooses
+	// boundaries that separate classes best and stops splitting values by
+	// the minimum description length principle. It decides the number of
+	// bins by itself.
+	MDL Method = "mdl"
+)
+
+// NewMethod converts a string to a Method.
+func NewMethod(s string) (Method, error) {
+	m := Method(s)
+	switch m {
+	case EqualWidth, EqualFrequency, MDL:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown discretization method '%s'", s)
+	}
+}
+
+// Binning contains settings for learning bins of a numeric feature.
+type Binning struct {
+	// Method is the algorithm for learning boundaries.
+	Method Method
+
+	// Bins is the number of bins. MDL method uses it as the maximum number
+	// of bins, if it is 0, the number of bins is not limited.
+	Bins int
+}
+
+// Check returns an error if the method is unknown or if the number of bins
+// is too small for the method.
+func (b Binning) Check() error {
+	if _, err := NewMethod(string(b.Method)); err != nil {
+		return err
+	}
+	if b.Method != MDL && b.Bins < 2 {
+		return fmt.Errorf("%s method needs at least 2 bins, got %d",
+			b.Method, b.Bins)
+	}
+	return nil
+}
+
+// Value is a numeric value from the training data.
+type Value struct {
+	// X is the number.
+	X float64
+
+	// Class is the class of the training data that contained the value.
+	Class ft.Class
+
+	// Weight is the weight of the training data that contained the value.
+	Weight float64
+}
+
+// Boundaries learns boundaries between bins from values. The boundaries
+// are sorted, and there is one boundary less than there are bins.
+func (b Binning) Boundaries(vals []Value) []float64 {
+	if len(vals) == 0 {
+		return nil
+	}
+	vals = slices.Clone(vals)
+	slices.SortFunc(vals, func(a, b Value) int {
+		return cmp.Compare(a.X, b.X)
+	})
+
+	switch b.Method {
+	case EqualFrequency:
+		return equalFrequency(vals, b.Bins)
+	case MDL:
+		var res []float64
+		res = mdl(vals, res)
+		slices.Sort(res)
+		if b.Bins > 0 && len(res) >= b.Bins {
+			res = reduce(vals, res, b.Bins)
+		}
+		return res
+	default:
+		return equalWidth(vals, b.Bins)
+	}
+}
+
+// Label returns the label of the bin that contains x. The label shows the
+// interval of the bin, for example '[2.5,4)'.
+func Label(boundaries []float64, x float64) ft.Value {
+	i := sort.Search(len(boundaries), func(j int) bool {
+		return boundaries[j] > x
+	})
+	lo, hi := math.Inf(-1), math.Inf(1)
+	if i > 0 {
+		lo = boundaries[i-1]
+	}
+	if i < len(boundaries) {
+		hi = boundaries[i]
+	}
+	return ft.Value(fmt.Sprintf("[%g,%g)", lo, hi))
+}
+
+func equalWidth(vals []Value, bins int) []float64 {
+	lo, hi := vals[0].X, vals[len(vals)-1].X
+	if bins < 2 || lo == hi {
+		return nil
+	}
+	res := make([]float64, bins-1)
+	width := (hi - lo) / float64(bins)
+	for i := range res {
+		res[i] = lo + width*float64(i+1)
+	}
+	return res
+}
+
+func equalFrequency(vals []Value, bins int) []float64 {
+	if bins < 2 {
+		return nil
+	}
+	var total float64
+	for _, v := range vals {
+		total += v.Weight
+	}
+
+	var res []float64
+	var cum float64
+	next := 1
+	for i := 0; i < len(vals)-1 && next < bins; i++ {
+		cum += vals[i].Weight
+		if vals[i].X == vals[i+1].X {
+			continue
+		}
+		if cum >= total*float64(next)/float64(bins) {
+			res = append(res, (vals[i].X+vals[i+1].X)/2)
+			for next < bins && cum >= total*float64(next)/float64(bins) {
+				next++
+			}
+		}
+	}
+	return res
+}
+
+// classWeights counts weights of classes in values.
+func classWeights(vals []Value) map[ft.Class]float64 {
+	res := make(map[ft.Class]float64)
+	for _, v := range vals {
+		res[v.Class] += v.Weight
+	}
+	return res
+}
+
+func entropy(weights map[ft.Class]float64, total float64) float64 {
+	var res float64
+	for _, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		p := w / total
+		res -= p * math.Log2(p)
+	}
+	return res
+}
+
+func nonZero(weights map[ft.Class]float64) int {
+	var res int
+	for _, w := range weights {
+		if w > 0 {
+			res++
+		}
+	}
+	return res
+}
+
+// bestCut finds the boundary that minimizes the weighted class entropy of
+// two parts of values. It returns the index of the first value of the
+// second part, or 0 if there are no possible boundaries.
+func bestCut(vals []Value) (int, float64) {
+	right := classWeights(vals)
+	left := make(map[ft.Class]float64)
+	var total float64
+	for _, w := range right {
+		total += w
+	}
+
+	var cut int
+	best := math.Inf(1)
+	var leftTotal float64
+	for i := 0; i < len(vals)-1; i++ {
+		v := vals[i]
+		left[v.Class] += v.Weight
+		right[v.Class] -= v.Weight
+		leftTotal += v.Weight
+		if v.X == vals[i+1].X {
+			continue
+		}
+		rightTotal := total - leftTotal
+		e := (leftTotal*entropy(left, leftTotal) +
+			rightTotal*entropy(right, rightTotal)) / total
+		if e < best {
+			best = e
+			cut = i + 1
+		}
+	}
+	return cut, best
+}
+
+// mdl recursively splits values according to the MDL principle.
+func mdl(vals []Value, res []float64) []float64 {
+	cut, cutEntropy := bestCut(vals)
+	if cut == 0 {
+		return res
+	}
+
+	weights := classWeights(vals)
+	// values of one class need no boundaries, rounding errors of weights
+	// must not create them.
+	if nonZero(weights) < 2 {
+		return res
+	}
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	leftWeights := classWeights(vals[:cut])
+	rightWeights := classWeights(vals[cut:])
+	var leftTotal float64
+	for _, w := range leftWeights {
+		leftTotal += w
+	}
+	rightTotal := total - leftTotal
+
+	ent := entropy(weights, total)
+	gain := ent - cutEntropy
+	k := float64(nonZero(weights))
+	k1 := float64(nonZero(leftWeights))
+	k2 := float64(nonZero(rightWeights))
+	delta := math.Log2(math.Pow(3, k)-2) - (k*ent -
+		k1*entropy(leftWeights, leftTotal) -
+		k2*entropy(rightWeights, rightTotal))
+	// weights below 2 in total would make the penalty undefined.
+	n := math.Max(total, 2)
+	if gain <= (math.Log2(n-1)+delta)/n {
+		return res
+	}
+
+	res = append(res, (vals[cut-1].X+vals[cut].X)/2)
+	res = mdl(vals[:cut], res)
+	return mdl(vals[cut:], res)
+}
+
+// reduce removes boundaries that separate classes worst, until there are
+// less boundaries than bins.
+func reduce(vals []Value, boundaries []float64, bins int) []float64 {
+	for len(boundaries) >= bins {
+		worst := -1
+		best := math.Inf(1)
+		for i := range boundaries {
+			rest := slices.Delete(slices.Clone(boundaries), i, i+1)
+			if e := binsEntropy(vals, rest); e < best {
+				best = e
+				worst = i
+			}
+		}
+		boundaries = slices.Delete(boundaries, worst, worst+1)
+	}
+	return boundaries
+}
+
+// binsEntropy returns the weighted class entropy of bins.
+func binsEntropy(vals []Value, boundaries []float64) float64 {
+	bins := make(map[ft.Value][]Value)
+	for _, v := range vals {
+		l := Label(boundaries, v.X)
+		bins[l] = append(bins[l], v)
+	}
+	var res, total float64
+	for _, b := range bins {
+		weights := classWeights(b)
+		var t float64
+		for _, w := range weights {
+			t += w
+		}
+		res += t * entropy(weights, t)
+		total += t
+	}
+	return res / total
+}
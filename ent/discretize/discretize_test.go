@@ -0,0 +1,117 @@
+package discretize_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gnames/bayes/ent/discretize"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func values(class ft.Class, xs ...float64) []discretize.Value {
+	res := make([]discretize.Value, len(xs))
+	for i, x := range xs {
+		res[i] = discretize.Value{X: x, Class: class, Weight: 1}
+	}
+	return res
+}
+
+func TestBoundaries(t *testing.T) {
+	t.Run("equal width", func(t *testing.T) {
+		b := discretize.Binning{Method: discretize.EqualWidth, Bins: 4}
+		vals := values("a", 8, 0, 1, 2)
+		assert.Equal(t, []float64{2, 4, 6}, b.Boundaries(vals))
+		assert.Nil(t, b.Boundaries(values("a", 3, 3, 3)))
+		assert.Nil(t, b.Boundaries(nil))
+	})
+
+	t.Run("equal frequency", func(t *testing.T) {
+		b := discretize.Binning{Method: discretize.EqualFrequency, Bins: 3}
+		vals := values("a", 6, 5, 4, 3, 2, 1)
+		assert.Equal(t, []float64{2.5, 4.5}, b.Boundaries(vals))
+
+		// the same values stay in one bin
+		vals = values("a", 1, 1, 1, 1, 2, 3)
+		assert.Equal(t, []float64{1.5}, b.Boundaries(vals))
+	})
+
+	t.Run("equal frequency with weights", func(t *testing.T) {
+		b := discretize.Binning{Method: discretize.EqualFrequency, Bins: 2}
+		vals := values("a", 1, 2, 3, 4)
+		vals[0].Weight = 3
+		assert.Equal(t, []float64{1.5}, b.Boundaries(vals))
+	})
+
+	t.Run("mdl", func(t *testing.T) {
+		b := discretize.Binning{Method: discretize.MDL}
+		vals := append(
+			values("a", 1, 2, 3, 4, 5, 6, 7, 8),
+			values("b", 11, 12, 13, 14, 15, 16, 17, 18)...,
+		)
+		assert.Equal(t, []float64{9.5}, b.Boundaries(vals))
+
+		vals = append(vals, values("c", 21, 22, 23, 24, 25, 26, 27, 28)...)
+		assert.Equal(t, []float64{9.5, 19.5}, b.Boundaries(vals))
+
+		b.Bins = 2
+		assert.Equal(t, 1, len(b.Boundaries(vals)))
+	})
+
+	t.Run("mdl with small weights", func(t *testing.T) {
+		b := discretize.Binning{Method: discretize.MDL}
+		vals := append(
+			values("a", 1, 2, 3, 4, 5, 6, 7, 8),
+			values("b", 11, 12, 13, 14, 15, 16, 17, 18)...,
+		)
+		for i := range vals {
+			vals[i].Weight = 0.01
+		}
+		assert.Equal(t, []float64{9.5}, b.Boundaries(vals))
+	})
+
+	t.Run("mdl does not split mixed classes", func(t *testing.T) {
+		b := discretize.Binning{Method: discretize.MDL}
+		vals := values("a", 1, 3, 5, 7, 9, 11)
+		vals = append(vals, values("b", 2, 4, 6, 8, 10, 12)...)
+		assert.Empty(t, b.Boundaries(vals))
+	})
+}
+
+func TestLabel(t *testing.T) {
+	bounds := []float64{2.5, 4}
+	tests := []struct {
+		x   float64
+		exp ft.Value
+	}{
+		{1, "[-Inf,2.5)"},
+		{2.5, "[2.5,4)"},
+		{3, "[2.5,4)"},
+		{4, "[4,+Inf)"},
+		{math.Inf(1), "[4,+Inf)"},
+	}
+	for _, v := range tests {
+		assert.Equal(t, v.exp, discretize.Label(bounds, v.x))
+	}
+	assert.Equal(t, ft.Value("[-Inf,+Inf)"), discretize.Label(nil, 1))
+}
+
+func TestNewMethod(t *testing.T) {
+	m, err := discretize.NewMethod("mdl")
+	assert.Nil(t, err)
+	assert.Equal(t, discretize.MDL, m)
+	_, err = discretize.NewMethod("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	b := discretize.Binning{Method: discretize.EqualWidth, Bins: 2}
+	assert.Nil(t, b.Check())
+	b.Bins = 1
+	assert.EqualError(t, b.Check(),
+		"equal-width method needs at least 2 bins, got 1")
+	b = discretize.Binning{Method: discretize.MDL}
+	assert.Nil(t, b.Check())
+	b.Method = "unknown"
+	assert.NotNil(t, b.Check())
+}
@@ -0,0 +1,334 @@
+// package distribution contains probability distributions of numeric
+// features. Every class learns its own distribution for a numeric feature
+// from the feature's values in the training data.
+package distribution
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// minVariance prevents zero variance when all training values are the
+// same.
+const minVariance = 1e-9
+
+// varSmoothing is the share of the variance of all values of a feature,
+// which is the minimal variance of a distribution of one class.
+const varSmoothing = 0.01
+
+// Kind is a type of a probability distribution.
+type Kind string
+
+const (
+	// Gaussian is the normal distribution.
+	Gaussian Kind = "gaussian"
+
+	// LogNormal is the distribution of values which logarithms are normally
+	// distributed. It is suitable for positive values with a long tail, like
+	// lengths or sizes.
+	LogNormal Kind = "log-normal"
+
+	// Poisson is the distribution of counts of events. Values must be
+	// non-negative.
+	Poisson Kind = "poisson"
+
+	// KDE is a kernel density estimation with Gaussian kernels. It does not
+	// assume any shape of the distribution, but keeps all distinct training
+	// values.
+	KDE Kind = "kde"
+)
+
+// NewKind converts a string to a Kind.
+func NewKind(s string) (Kind, error) {
+	k := Kind(s)
+	switch k {
+	case Gaussian, LogNormal, Poisson, KDE:
+		return k, nil
+	default:
+		return "", fmt.Errorf("unknown distribution '%s'", s)
+	}
+}
+
+// Check returns an error if a value cannot belong to the distribution.
+func (k Kind) Check(x float64) error {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return fmt.Errorf("value %g is not finite", x)
+	}
+	switch k {
+	case LogNormal:
+		if x <= 0 {
+			return fmt.Errorf("value %g is not positive", x)
+		}
+	case Poisson:
+		if x < 0 {
+			return fmt.Errorf("value %g is negative", x)
+		}
+	}
+	return nil
+}
+
+// Stats contain statistics of numeric values that are sufficient to
+// build their distribution. Stats can be added and subtracted, which
+// allows incremental training and removal of training data. Instead of
+// sums of squares Stats keep sums of squared deviations from the mean,
+// which preserves precision for large values, like coordinates.
+type Stats struct {
+	// Count is the weighted number of values.
+	Count float64
+
+	// mean is the weighted mean of values.
+	mean float64
+
+	// m2 is the weighted sum of squared deviations from the mean.
+	m2 float64
+
+	// logMean is the weighted mean of logarithms of values. It is kept
+	// only for the LogNormal distribution.
+	logMean float64
+
+	// logM2 is the weighted sum of squared deviations of logarithms of
+	// values from logMean.
+	logM2 float64
+
+	// minVar and minLogVar are minimal variances of values and of their
+	// logarithms, set by Smooth.
+	minVar, minLogVar float64
+
+	// Samples are weights of distinct values. They are kept only for
+	// the KDE distribution.
+	Samples map[float64]float64
+}
+
+// NewStats creates statistics for one value with the given weight.
+func NewStats(kind Kind, x, weight float64) Stats {
+	res := Stats{Count: weight, mean: x}
+	if kind == LogNormal && x > 0 {
+		res.logMean = math.Log(x)
+	}
+	if kind == KDE {
+		res.Samples = map[float64]float64{x: weight}
+	}
+	return res
+}
+
+// Add returns the sum of two statistics. Means and squared deviations are
+// merged by the parallel algorithm of Chan et al.
+func (s Stats) Add(o Stats) Stats {
+	var res Stats
+	res.Count = s.Count + o.Count
+	if res.Count > 0 {
+		res.mean, res.m2 = merge(s.Count, s.mean, s.m2, o.Count, o.mean, o.m2)
+		res.logMean, res.logM2 = merge(
+			s.Count, s.logMean, s.logM2, o.Count, o.logMean, o.logM2,
+		)
+	}
+	if s.Samples != nil || o.Samples != nil {
+		res.Samples = make(map[float64]float64, len(s.Samples)+len(o.Samples))
+		for k, v := range s.Samples {
+			res.Samples[k] += v
+		}
+		for k, v := range o.Samples {
+			res.Samples[k] += v
+		}
+	}
+	return res
+}
+
+// Sub returns the difference of two statistics. Samples with zero or
+// negative weights are removed. The caller has to make sure that the
+// statistics to subtract are a part of the original ones.
+func (s Stats) Sub(o Stats, epsilon float64) Stats {
+	var res Stats
+	res.Count = s.Count - o.Count
+	if res.Count > epsilon {
+		res.mean, res.m2 = unmerge(s.Count, s.mean, s.m2, o.Count, o.mean, o.m2)
+		res.logMean, res.logM2 = unmerge(
+			s.Count, s.logMean, s.logM2, o.Count, o.logMean, o.logM2,
+		)
+	}
+	if s.Samples != nil {
+		res.Samples = make(map[float64]float64, len(s.Samples))
+		for k, v := range s.Samples {
+			if w := v - o.Samples[k]; w > epsilon {
+				res.Samples[k] = w
+			}
+		}
+	}
+	return res
+}
+
+// merge returns the mean and the sum of squared deviations of two merged
+// groups of values.
+func merge(na, ma, m2a, nb, mb, m2b float64) (float64, float64) {
+	n := na + nb
+	d := mb - ma
+	return ma + d*nb/n, m2a + m2b + d*d*na*nb/n
+}
+
+// unmerge is the inverse of merge. It returns the mean and the sum of
+// squared deviations of the first group from the merged group and the
+// second group.
+func unmerge(n, m, m2, nb, mb, m2b float64) (float64, float64) {
+	na := n - nb
+	ma := m - (mb-m)*nb/na
+	d := mb - ma
+	return ma, math.Max(m2-m2b-d*d*na*nb/n, 0)
+}
+
+// Contains returns an error if the statistics do not contain enough values
+// to subtract other statistics from them.
+func (s Stats) Contains(o Stats, epsilon float64) error {
+	if s.Count < o.Count-epsilon {
+		return fmt.Errorf("cannot remove %g values, there are only %g",
+			o.Count, s.Count)
+	}
+	for k, v := range o.Samples {
+		if have := s.Samples[k]; have < v-epsilon {
+			return fmt.Errorf(
+				"cannot remove %g values of %g, there are only %g", v, k, have,
+			)
+		}
+	}
+	return nil
+}
+
+// Smooth returns the statistics with minimal variances that are a share
+// of variances of all values of the feature, given by total statistics.
+// It prevents extreme densities for a class with one value or with the
+// same values, which otherwise get nearly zero variance.
+func (s Stats) Smooth(total Stats) Stats {
+	if total.Count > 0 {
+		s.minVar = varSmoothing * total.m2 / total.Count
+		s.minLogVar = varSmoothing * total.logM2 / total.Count
+	}
+	return s
+}
+
+// Mean returns the weighted mean of values.
+func (s Stats) Mean() float64 {
+	return s.mean
+}
+
+// Variance returns the weighted variance of values. It is not less than
+// the minimal variance set by Smooth.
+func (s Stats) Variance() float64 {
+	return max(s.m2/s.Count, s.minVar, minVariance)
+}
+
+// LogPDF returns the natural logarithm of the probability density of a
+// value for a distribution of the given kind. For the Poisson distribution
+// it returns the logarithm of the probability.
+func (s Stats) LogPDF(kind Kind, x float64) float64 {
+	switch kind {
+	case LogNormal:
+		if x <= 0 {
+			return math.Inf(-1)
+		}
+		v := max(s.logM2/s.Count, s.minLogVar, minVariance)
+		lx := math.Log(x)
+		return logNormal(lx, s.logMean, v) - lx
+	case Poisson:
+		if x < 0 {
+			return math.Inf(-1)
+		}
+		lambda := math.Max(s.Mean(), minVariance)
+		lg, _ := math.Lgamma(x + 1)
+		return x*math.Log(lambda) - lambda - lg
+	case KDE:
+		return s.logKDE(x)
+	default:
+		return logNormal(x, s.Mean(), s.Variance())
+	}
+}
+
+// logKDE estimates the logarithm of the density with Gaussian kernels.
+// The bandwidth is calculated by the Silverman's rule of thumb.
+func (s Stats) logKDE(x float64) float64 {
+	h := 1.06 * math.Sqrt(s.Variance()) * math.Pow(s.Count, -0.2)
+	v := h * h
+
+	logs := make([]float64, 0, len(s.Samples))
+	for xi, w := range s.Samples {
+		logs = append(logs, math.Log(w)+logNormal(x, xi, v))
+	}
+	return logSumExp(logs) - math.Log(s.Count)
+}
+
+func logNormal(x, mean, variance float64) float64 {
+	d := x - mean
+	return -0.5*math.Log(2*math.Pi*variance) - d*d/(2*variance)
+}
+
+func logSumExp(logs []float64) float64 {
+	if len(logs) == 0 {
+		return math.Inf(-1)
+	}
+	m := slices.Max(logs)
+	if math.IsInf(m, 0) {
+		return m
+	}
+	var sum float64
+	for _, v := range logs {
+		sum += math.Exp(v - m)
+	}
+	return m + math.Log(sum)
+}
+
+// Sample is a distinct value with its weight.
+type Sample struct {
+	Value  float64 `json:"value"`
+	Weight float64 `json:"weight"`
+}
+
+type statsJSON struct {
+	Count   float64  `json:"count"`
+	Mean    float64  `json:"mean"`
+	M2      float64  `json:"m2"`
+	LogMean float64  `json:"logMean,omitempty"`
+	LogM2   float64  `json:"logM2,omitempty"`
+	Samples []Sample `json:"samples,omitempty"`
+}
+
+// MarshalJSON serializes Stats to JSON. Samples are sorted by their
+// values.
+func (s Stats) MarshalJSON() ([]byte, error) {
+	res := statsJSON{
+		Count:   s.Count,
+		Mean:    s.mean,
+		M2:      s.m2,
+		LogMean: s.logMean,
+		LogM2:   s.logM2,
+	}
+	for k, v := range s.Samples {
+		res.Samples = append(res.Samples, Sample{Value: k, Weight: v})
+	}
+	slices.SortFunc(res.Samples, func(a, b Sample) int {
+		return cmp.Compare(a.Value, b.Value)
+	})
+	return json.Marshal(res)
+}
+
+// UnmarshalJSON deserializes Stats from JSON.
+func (s *Stats) UnmarshalJSON(data []byte) error {
+	var res statsJSON
+	if err := json.Unmarshal(data, &res); err != nil {
+		return err
+	}
+	*s = Stats{
+		Count:   res.Count,
+		mean:    res.Mean,
+		m2:      res.M2,
+		logMean: res.LogMean,
+		logM2:   res.LogM2,
+	}
+	if len(res.Samples) > 0 {
+		s.Samples = make(map[float64]float64, len(res.Samples))
+		for _, v := range res.Samples {
+			s.Samples[v.Value] += v.Weight
+		}
+	}
+	return nil
+}
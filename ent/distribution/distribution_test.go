@@ -0,0 +1,154 @@
+package distribution_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/gnames/bayes/ent/distribution"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStats(kind distribution.Kind, xs ...float64) distribution.Stats {
+	var res distribution.Stats
+	for _, x := range xs {
+		res = res.Add(distribution.NewStats(kind, x, 1))
+	}
+	return res
+}
+
+func TestLogPDF(t *testing.T) {
+	t.Run("gaussian", func(t *testing.T) {
+		st := newStats(distribution.Gaussian, 1, 2, 3, 4, 5)
+		assert.Equal(t, 3.0, st.Mean())
+		assert.Equal(t, 2.0, st.Variance())
+		exp := -0.5 * math.Log(2*math.Pi*2)
+		assert.InDelta(t, exp, st.LogPDF(distribution.Gaussian, 3), 1e-9)
+		assert.InDelta(t, exp-0.25, st.LogPDF(distribution.Gaussian, 4), 1e-9)
+	})
+
+	t.Run("gaussian with the same values", func(t *testing.T) {
+		st := newStats(distribution.Gaussian, 2, 2, 2)
+		res := st.LogPDF(distribution.Gaussian, 2)
+		assert.False(t, math.IsInf(res, 0))
+		assert.Greater(t, res, st.LogPDF(distribution.Gaussian, 2.001))
+	})
+
+	t.Run("smoothed variance", func(t *testing.T) {
+		st := newStats(distribution.Gaussian, 2, 2, 2)
+		total := st.Add(newStats(distribution.Gaussian, 4, 4, 4))
+		assert.Equal(t, 1.0, total.Variance())
+		assert.InDelta(t, 0.01, st.Smooth(total).Variance(), 1e-12)
+		assert.Equal(t, 1.0, total.Smooth(total).Variance())
+	})
+
+	t.Run("log-normal", func(t *testing.T) {
+		st := newStats(distribution.LogNormal, 1, math.E, math.E*math.E)
+		x := math.E
+		exp := -0.5*math.Log(2*math.Pi*2.0/3) - 1
+		assert.InDelta(t, exp, st.LogPDF(distribution.LogNormal, x), 1e-9)
+		assert.True(t, math.IsInf(st.LogPDF(distribution.LogNormal, -1), -1))
+	})
+
+	t.Run("poisson", func(t *testing.T) {
+		st := newStats(distribution.Poisson, 1, 2, 3)
+		exp := math.Log(math.Pow(2, 3) * math.Exp(-2) / 6)
+		assert.InDelta(t, exp, st.LogPDF(distribution.Poisson, 3), 1e-9)
+	})
+
+	t.Run("kde", func(t *testing.T) {
+		st := newStats(distribution.KDE, 1, 1.5, 2, 8, 8.5, 9)
+		assert.Equal(t, 6, len(st.Samples))
+		assert.Greater(t,
+			st.LogPDF(distribution.KDE, 1.5), st.LogPDF(distribution.KDE, 5))
+		assert.Greater(t,
+			st.LogPDF(distribution.KDE, 8.5), st.LogPDF(distribution.KDE, 5))
+
+		// density integrates to 1
+		var sum float64
+		step := 0.01
+		for x := -10.0; x < 20; x += step {
+			sum += math.Exp(st.LogPDF(distribution.KDE, x)) * step
+		}
+		assert.InDelta(t, 1.0, sum, 1e-3)
+	})
+}
+
+func TestAddSub(t *testing.T) {
+	st1 := newStats(distribution.KDE, 1, 2, 3)
+	st2 := newStats(distribution.KDE, 3, 4)
+	sum := st1.Add(st2)
+	assertSameStats(t, newStats(distribution.KDE, 1, 2, 3, 3, 4), sum)
+	assert.Nil(t, sum.Contains(st2, 1e-9))
+	assertSameStats(t, st1, sum.Sub(st2, 1e-9))
+
+	ln1 := newStats(distribution.LogNormal, 1, 2, 3)
+	ln2 := newStats(distribution.LogNormal, 5, 8)
+	lnSum := ln1.Add(ln2)
+	x := 2.5
+	assert.InDelta(t,
+		newStats(distribution.LogNormal, 1, 2, 3, 5, 8).
+			LogPDF(distribution.LogNormal, x),
+		lnSum.LogPDF(distribution.LogNormal, x), 1e-9)
+	assert.InDelta(t,
+		ln1.LogPDF(distribution.LogNormal, x),
+		lnSum.Sub(ln2, 1e-9).LogPDF(distribution.LogNormal, x), 1e-9)
+
+	err := st1.Contains(st2, 1e-9)
+	assert.EqualError(t, err, "cannot remove 1 values of 4, there are only 0")
+	err = st2.Contains(sum, 1e-9)
+	assert.EqualError(t, err, "cannot remove 5 values, there are only 2")
+}
+
+func TestPrecision(t *testing.T) {
+	st := newStats(distribution.Gaussian, 1e8, 1e8+1, 1e8+2)
+	assert.Equal(t, 1e8+1, st.Mean())
+	assert.InDelta(t, 2.0/3, st.Variance(), 1e-9)
+
+	more := newStats(distribution.Gaussian, 1e8+3, 1e8+4)
+	st = st.Add(more)
+	assert.InDelta(t, 2.0, st.Variance(), 1e-9)
+	st = st.Sub(more, 1e-9)
+	assert.InDelta(t, 1e8+1, st.Mean(), 1e-9)
+	assert.InDelta(t, 2.0/3, st.Variance(), 1e-9)
+}
+
+func assertSameStats(t *testing.T, st1, st2 distribution.Stats) {
+	assert.InDelta(t, st1.Count, st2.Count, 1e-9)
+	assert.InDelta(t, st1.Mean(), st2.Mean(), 1e-9)
+	assert.InDelta(t, st1.Variance(), st2.Variance(), 1e-9)
+	assert.Equal(t, st1.Samples, st2.Samples)
+}
+
+func TestJSON(t *testing.T) {
+	st := newStats(distribution.KDE, 3, 1, 2, 2)
+	res, err := json.Marshal(st)
+	assert.Nil(t, err)
+	assert.Contains(t, string(res), `"samples":[{"value":1,"weight":1},`)
+
+	var st2 distribution.Stats
+	err = json.Unmarshal(res, &st2)
+	assert.Nil(t, err)
+	assert.Equal(t, st, st2)
+
+	st = newStats(distribution.Gaussian, 3, 1)
+	res, err = json.Marshal(st)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(res), "samples")
+}
+
+func TestKind(t *testing.T) {
+	for _, v := range []string{"gaussian", "log-normal", "poisson", "kde"} {
+		k, err := distribution.NewKind(v)
+		assert.Nil(t, err)
+		assert.Equal(t, distribution.Kind(v), k)
+	}
+	_, err := distribution.NewKind("cauchy")
+	assert.EqualError(t, err, "unknown distribution 'cauchy'")
+
+	assert.Nil(t, distribution.Gaussian.Check(-1))
+	assert.EqualError(t, distribution.LogNormal.Check(0), "value 0 is not positive")
+	assert.EqualError(t, distribution.Poisson.Check(-1), "value -1 is negative")
+	assert.EqualError(t, distribution.KDE.Check(math.NaN()),
+		"value NaN is not finite")
+}
@@ -3,6 +3,47 @@ package feature
 type ClassFeatures struct {
 	Class
 	Features []Feature
+
+	// Weight is the number of cases the feature set represents during
+	// training. It allows to make some of the training data more important
+	// than the rest. If Weight is nil, the feature set counts as one case.
+	// A feature set with zero weight does not count at all.
+	Weight *float64
+}
+
+// CaseWeight returns the weight of a feature set. It returns 1 if the
+// weight is not set.
+func (cf ClassFeatures) CaseWeight() float64 {
+	if cf.Weight == nil {
+		return 1
+	}
+	return *cf.Weight
+}
+
+// MultiClassFeatures is a feature set that belongs to several classes at
+// once. Classes of such feature sets are called labels.
+type MultiClassFeatures struct {
+	Classes  []Class
+	Features []Feature
+
+	// Weight is the number of cases the feature set represents during
+	// training. If Weight is nil, the feature set counts as one case.
+	Weight *float64
+}
+
+// CaseWeight returns the weight of a feature set. It returns 1 if the
+// weight is not set.
+func (cf MultiClassFeatures) CaseWeight() float64 {
+	if cf.Weight == nil {
+		return 1
+	}
+	return *cf.Weight
+}
+
+// Weight returns a weight of a feature set, ready to be used in
+// ClassFeatures or MultiClassFeatures.
+func Weight(w float64) *float64 {
+	return &w
 }
 
 type Feature struct {
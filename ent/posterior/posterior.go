@@ -1,6 +1,13 @@
 package posterior
 
-import ft "github.com/gnames/bayes/ent/feature"
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+
+	ft "github.com/gnames/bayes/ent/feature"
+)
 
 // Odds are calculated posterior odds to classify an entity according to
 // all the used features it contains.
@@ -8,6 +15,16 @@ import ft "github.com/gnames/bayes/ent/feature"
 // MaxOdds provides the best odds calculated for an entity and
 // MaxClass provide the category with the best odds. This class is
 // the desired classification result.
+//
+// Odds are calculated as natural logarithms, which prevents overflow and
+// underflow of the results for large sets of features. ClassOdds, MaxOdds
+// and Likelihoods are exponents of LogOdds, MaxLogOdds and LogLikelihoods
+// correspondingly. They might become +Inf or 0 for extreme results, while
+// the logarithmic values stay meaningful.
+//
+// The Complement model does not calculate odds or probabilities. Its
+// results are in Scores, while fields of odds, likelihoods and
+// probabilities stay empty.
 type Odds struct {
 	// ClassOdds provide odds for each class.
 	ClassOdds map[ft.Class]float64
@@ -18,13 +35,275 @@ type Odds struct {
 	// MaxOdds is the odds of the MaxClass
 	MaxOdds float64
 
+	// LogOdds provide natural logarithms of odds for each class.
+	LogOdds map[ft.Class]float64
+
+	// MaxLogOdds is the natural logarithm of the odds of the MaxClass.
+	MaxLogOdds float64
+
+	// LogLikelihoods are natural logarithms of Likelihoods.
+	LogLikelihoods Likelihoods
+
+	// ClassProbs provide posterior probabilities for each class. In
+	// contrast to ClassOdds, that compare each class to all the rest,
+	// ClassProbs are a distribution over all classes and sum up to 1.
+	// They are probabilities from ClassOdds normalized by their sum, so
+	// the MaxClass is the most probable class, and the ranking, abstaining
+	// and costs agree with each other.
+	ClassProbs map[ft.Class]float64
+
+	// NodeProbs provide probabilities of all classes of a hierarchy of
+	// classes. Probability of a class is the sum of its own probability
+	// and probabilities of its descendants. It is empty if there is no
+	// hierarchy.
+	NodeProbs map[ft.Class]float64
+
+	// Path contains classes from the top of the hierarchy to the deepest
+	// class which probability passed the threshold. On every level the
+	// child with the highest probability is selected. It is empty if
+	// there is no hierarchy.
+	Path []ft.Class
+
+	// Unknown are features that are not known from training. Repeated
+	// features are listed once.
+	Unknown []ft.Feature
+
+	// Coverage is the ratio of known features to all features. It is 1 if
+	// there are no features.
+	Coverage float64
+
+	// Ranking contains classes sorted by decreasing odds. Classes with the
+	// same odds are sorted by their names. It might be limited to the
+	// top classes.
+	Ranking []Rank
+
+	// Margin is the difference between natural logarithms of odds of the
+	// first and the second classes of the ranking. For the Complement
+	// model it is the difference between their scores.
+	Margin float64
+
+	// Abstained is true if the evidence is too weak for a decision. In
+	// such case MaxClass is still the best guess, but it should not be
+	// trusted.
+	Abstained bool
+
+	// AbstainReason explains why the classification abstained.
+	AbstainReason Reason
+
+	// MinCostClass is the class with the minimal expected cost of
+	// misclassification. It is empty if costs are not given.
+	MinCostClass ft.Class
+
+	// ExpectedCosts provide expected costs of deciding for each class. It
+	// is empty if costs are not given.
+	ExpectedCosts map[ft.Class]float64
+
+	// PriorProbs provide prior probabilities of classes used in the
+	// calculation, including the ones from options and providers of
+	// priors. It is empty if prior odds are ignored.
+	PriorProbs map[ft.Class]float64
+
+	// Scores provide scores of classes of the Complement model. The class
+	// with the highest score is the MaxClass. It is empty for other
+	// models.
+	Scores map[ft.Class]float64
+
 	ClassCases
 	Likelihoods
 }
 
-// ClassCases is the number of cases per each class. They are used for the
-// final calculation of the prior odds.
-type ClassCases map[ft.Class]int
+// Rank is a place of a class in the ranking of classes.
+type Rank struct {
+	// Class is the ranked class.
+	Class ft.Class
+
+	// Odds is the odds of the class.
+	Odds float64
+
+	// LogOdds is the natural logarithm of the odds of the class.
+	LogOdds float64
+
+	// Prob is the probability of the class from ClassProbs.
+	Prob float64
+
+	// Score is the score of the class from Scores.
+	Score float64
+}
+
+// Rank sorts classes by decreasing LogOdds, or by decreasing Scores if
+// they are given, and sets Ranking and Margin. If k is positive, only k
+// top classes are kept in the ranking. Odds of classes in the ranking are
+// set by Exp.
+func (o *Odds) Rank(k int) {
+	vals := o.LogOdds
+	if o.Scores != nil {
+		vals = o.Scores
+	}
+	n := len(vals)
+	if k <= 0 || k > n {
+		k = n
+	}
+	// the second class is needed for the margin.
+	keep := max(k, min(2, n))
+	res := make([]Rank, 0, keep+1)
+	for class, v := range vals {
+		r := Rank{Class: class, LogOdds: v, Prob: o.ClassProbs[class]}
+		if o.Scores != nil {
+			r = Rank{Class: class, Score: v}
+		}
+		i, _ := slices.BinarySearchFunc(res, r, compareRanks)
+		if i >= keep {
+			continue
+		}
+		res = slices.Insert(res, i, r)
+		if len(res) > keep {
+			res = res[:keep]
+		}
+	}
+
+	o.Margin = math.Inf(1)
+	if len(res) > 1 {
+		o.Margin = res[0].LogOdds - res[1].LogOdds
+		if o.Scores != nil {
+			o.Margin = res[0].Score - res[1].Score
+		}
+	}
+	o.Ranking = res[:k]
+}
+
+func compareRanks(a, b Rank) int {
+	if c := cmp.Compare(b.LogOdds, a.LogOdds); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(b.Score, a.Score); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Class, b.Class)
+}
+
+// Costs are costs of misclassification. Costs[actual][decided] is the cost
+// of deciding for a class when the entity belongs to the actual class.
+// Missing costs are 0 for correct decisions and 1 for wrong ones.
+type Costs map[ft.Class]map[ft.Class]float64
+
+// Cost returns the cost of deciding for a class when the entity belongs
+// to the actual class.
+func (c Costs) Cost(actual, decided ft.Class) float64 {
+	if v, ok := c[actual][decided]; ok {
+		return v
+	}
+	if actual == decided {
+		return 0
+	}
+	return 1
+}
+
+// MinCost calculates expected costs of deciding for each class from
+// ClassProbs and sets ExpectedCosts and MinCostClass. Classes with the
+// same costs are decided by their names.
+func (o *Odds) MinCost(costs Costs) {
+	classes := slices.Sorted(maps.Keys(o.ClassProbs))
+	o.ExpectedCosts = make(map[ft.Class]float64, len(classes))
+	o.MinCostClass = ""
+	for _, decided := range classes {
+		var cost float64
+		for _, actual := range classes {
+			cost += o.ClassProbs[actual] * costs.Cost(actual, decided)
+		}
+		o.ExpectedCosts[decided] = cost
+		if o.MinCostClass == "" || cost < o.ExpectedCosts[o.MinCostClass] {
+			o.MinCostClass = decided
+		}
+	}
+}
+
+// Exp calculates ClassOdds, MaxOdds and Likelihoods from their logarithmic
+// counterparts.
+func (o *Odds) Exp() {
+	o.ClassOdds = make(map[ft.Class]float64, len(o.LogOdds))
+	for k, v := range o.LogOdds {
+		o.ClassOdds[k] = math.Exp(v)
+	}
+	o.MaxOdds = math.Exp(o.MaxLogOdds)
+	for i := range o.Ranking {
+		o.Ranking[i].Odds = math.Exp(o.Ranking[i].LogOdds)
+	}
+
+	o.Likelihoods = make(Likelihoods, len(o.LogLikelihoods))
+	for class, fv := range o.LogLikelihoods {
+		o.Likelihoods[class] = make(map[ft.Feature]float64, len(fv))
+		for f, v := range fv {
+			o.Likelihoods[class][f] = math.Exp(v)
+		}
+	}
+}
+
+// Reason explains why a classification abstained from a decision.
+type Reason string
+
+const (
+	// LowPosterior means that probability of the best class is too low.
+	LowPosterior Reason = "low posterior"
+
+	// LowMargin means that the best class is too close to the second best
+	// class.
+	LowMargin Reason = "low margin"
+
+	// FewFeatures means that there are too few known features.
+	FewFeatures Reason = "few known features"
+)
+
+// Labels are results of multi-label classification. Every label is
+// decided separately by comparing its probability with a threshold.
+type Labels struct {
+	// Labels are the labels with probabilities not less than the
+	// threshold, sorted by decreasing odds.
+	Labels []ft.Class
+
+	// LabelOdds provide odds of presence for each label.
+	LabelOdds map[ft.Class]float64
+
+	// LogOdds provide natural logarithms of LabelOdds.
+	LogOdds map[ft.Class]float64
+
+	// LabelProbs provide probabilities of presence for each label. They
+	// do not sum up to 1, because labels do not exclude each other.
+	LabelProbs map[ft.Class]float64
+
+	// Threshold is the minimal probability of a label in Labels.
+	Threshold float64
+}
+
+// OddsToProb converts odds to a probability.
+func OddsToProb(odds float64) float64 {
+	if math.IsInf(odds, 1) {
+		return 1
+	}
+	return odds / (1 + odds)
+}
+
+// ProbToOdds converts a probability to odds.
+func ProbToOdds(p float64) float64 {
+	return p / (1 - p)
+}
+
+// LogOddsToProb converts the natural logarithm of odds to a probability.
+func LogOddsToProb(logOdds float64) float64 {
+	if logOdds >= 0 {
+		return 1 / (1 + math.Exp(-logOdds))
+	}
+	e := math.Exp(logOdds)
+	return e / (1 + e)
+}
+
+// ProbToLogOdds converts a probability to the natural logarithm of odds.
+func ProbToLogOdds(p float64) float64 {
+	return math.Log(p) - math.Log1p(-p)
+}
+
+// ClassCases is the number of cases per each class. They come from
+// training, numbers of cases given by prior options replace them.
+type ClassCases map[ft.Class]float64
 
 // Likelihoods are the odds for each feachure for every class.
 // The multiplication product of all odds is the final odds for a class.
@@ -0,0 +1,106 @@
+package posterior_test
+
+import (
+	"math"
+	"testing"
+
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/gnames/bayes/ent/posterior"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversions(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0.75, posterior.OddsToProb(3))
+	assert.Equal(1.0, posterior.OddsToProb(math.Inf(1)))
+	assert.Equal(3.0, posterior.ProbToOdds(0.75))
+	assert.InDelta(0.75, posterior.LogOddsToProb(math.Log(3)), 1e-9)
+	assert.InDelta(math.Log(3), posterior.ProbToLogOdds(0.75), 1e-9)
+	assert.InDelta(0.0, posterior.LogOddsToProb(-1000), 1e-9)
+	assert.Equal(1.0, posterior.LogOddsToProb(1000))
+	assert.Equal(0.5, posterior.LogOddsToProb(0))
+}
+
+func TestRank(t *testing.T) {
+	newOdds := func() posterior.Odds {
+		return posterior.Odds{
+			LogOdds: map[ft.Class]float64{
+				"a": 1, "b": 3, "c": 1, "d": -2, "e": 2.5,
+			},
+			ClassProbs: map[ft.Class]float64{"b": 0.6},
+		}
+	}
+
+	t.Run("sorts all classes", func(t *testing.T) {
+		o := newOdds()
+		o.Rank(0)
+		var classes []ft.Class
+		for _, v := range o.Ranking {
+			classes = append(classes, v.Class)
+		}
+		assert.Equal(t, []ft.Class{"b", "e", "a", "c", "d"}, classes)
+		assert.Equal(t, 0.5, o.Margin)
+		assert.Equal(t, 0.6, o.Ranking[0].Prob)
+
+		o.Exp()
+		assert.Equal(t, math.Exp(3), o.Ranking[0].Odds)
+	})
+
+	t.Run("keeps top classes", func(t *testing.T) {
+		o := newOdds()
+		o.Rank(1)
+		assert.Equal(t, 1, len(o.Ranking))
+		assert.Equal(t, ft.Class("b"), o.Ranking[0].Class)
+		assert.Equal(t, 0.5, o.Margin)
+
+		o.Rank(10)
+		assert.Equal(t, 5, len(o.Ranking))
+	})
+
+	t.Run("has infinite margin for one class", func(t *testing.T) {
+		o := posterior.Odds{LogOdds: map[ft.Class]float64{"a": 1}}
+		o.Rank(0)
+		assert.Equal(t, 1, len(o.Ranking))
+		assert.True(t, math.IsInf(o.Margin, 1))
+	})
+
+	t.Run("sorts scores", func(t *testing.T) {
+		o := posterior.Odds{Scores: map[ft.Class]float64{"a": 0.2, "b": 0.5}}
+		o.Rank(0)
+		assert.Equal(t, ft.Class("b"), o.Ranking[0].Class)
+		assert.Equal(t, 0.5, o.Ranking[0].Score)
+		assert.Equal(t, 0.0, o.Ranking[0].LogOdds)
+		assert.InDelta(t, 0.3, o.Margin, 1e-9)
+	})
+}
+
+func TestMinCost(t *testing.T) {
+	o := posterior.Odds{
+		ClassProbs: map[ft.Class]float64{"name": 0.3, "noise": 0.7},
+	}
+
+	t.Run("uses zero-one costs by default", func(t *testing.T) {
+		o.MinCost(nil)
+		assert.Equal(t, ft.Class("noise"), o.MinCostClass)
+		assert.InDelta(t, 0.7, o.ExpectedCosts["name"], 1e-9)
+		assert.InDelta(t, 0.3, o.ExpectedCosts["noise"], 1e-9)
+	})
+
+	t.Run("uses costs", func(t *testing.T) {
+		costs := posterior.Costs{"name": {"noise": 10}}
+		assert.Equal(t, 10.0, costs.Cost("name", "noise"))
+		assert.Equal(t, 1.0, costs.Cost("noise", "name"))
+		assert.Equal(t, 0.0, costs.Cost("name", "name"))
+
+		o.MinCost(costs)
+		assert.Equal(t, ft.Class("name"), o.MinCostClass)
+		assert.InDelta(t, 0.7, o.ExpectedCosts["name"], 1e-9)
+		assert.InDelta(t, 3.0, o.ExpectedCosts["noise"], 1e-9)
+	})
+
+	t.Run("decides ties by names", func(t *testing.T) {
+		o.MinCost(posterior.Costs{"noise": {"name": 3.0 / 7}})
+		assert.Equal(t, ft.Class("name"), o.MinCostClass)
+	})
+}
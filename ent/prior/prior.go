@@ -0,0 +1,86 @@
+// package prior provides prior probabilities of classes that depend on the
+// context of classification, for example on a region or a source of data.
+package prior
+
+import (
+	ft "github.com/gnames/bayes/ent/feature"
+)
+
+// Provider returns prior probabilities of classes for a classification
+// call. Providers are called during classification, so they must not call
+// methods of the Bayes object.
+type Provider interface {
+	// Priors takes a key given by the caller of classification and
+	// features of the classified entity, and returns prior probabilities
+	// of some classes. Classes that are not in the result share the rest of
+	// the probability according to training data. If the result is nil,
+	// prior probabilities from training are used.
+	Priors(key string, fs []ft.Feature) (map[ft.Class]float64, error)
+}
+
+// Lookup is a table of prior probabilities of classes by a key given by the
+// caller of classification, for example by a name of a region.
+type Lookup map[string]map[ft.Class]float64
+
+// Priors returns prior probabilities for the key. It returns nil for
+// unknown keys.
+func (l Lookup) Priors(
+	key string,
+	_ []ft.Feature,
+) (map[ft.Class]float64, error) {
+	return l[key], nil
+}
+
+// FeatureLookup is a table of prior probabilities of classes by a value of
+// a feature of the classified entity.
+type FeatureLookup struct {
+	// Name is the name of the feature.
+	Name ft.Name
+
+	// Table contains prior probabilities for values of the feature.
+	Table map[ft.Value]map[ft.Class]float64
+}
+
+// Priors returns prior probabilities for the first value of the feature
+// that is in the table. It returns nil if there is no such value.
+func (l FeatureLookup) Priors(
+	_ string,
+	fs []ft.Feature,
+) (map[ft.Class]float64, error) {
+	for _, f := range fs {
+		if f.Name != l.Name {
+			continue
+		}
+		if res, ok := l.Table[f.Value]; ok {
+			return res, nil
+		}
+	}
+	return nil, nil
+}
+
+// Fallback asks providers in turn and returns the first result. If no
+// provider has a result, prior probabilities from training are used.
+type Fallback []Provider
+
+// Priors returns the first result of providers, or nil if there is none.
+// It stops at the first error.
+func (f Fallback) Priors(
+	key string,
+	fs []ft.Feature,
+) (map[ft.Class]float64, error) {
+	for _, p := range f {
+		res, err := p.Priors(key, fs)
+		if err != nil || res != nil {
+			return res, err
+		}
+	}
+	return nil, nil
+}
+
+// Training always uses prior probabilities from training.
+type Training struct{}
+
+// Priors returns nil.
+func (Training) Priors(string, []ft.Feature) (map[ft.Class]float64, error) {
+	return nil, nil
+}
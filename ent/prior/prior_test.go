@@ -0,0 +1,65 @@
+package prior_test
+
+import (
+	"errors"
+	"testing"
+
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/gnames/bayes/ent/prior"
+	"github.com/stretchr/testify/assert"
+)
+
+type failing struct{}
+
+func (failing) Priors(string, []ft.Feature) (map[ft.Class]float64, error) {
+	return nil, errors.New("no connection")
+}
+
+func TestProviders(t *testing.T) {
+	south := map[ft.Class]float64{"penguin": 0.9}
+	north := map[ft.Class]float64{"penguin": 0.01}
+	lookup := prior.Lookup{"south": south}
+	features := prior.FeatureLookup{
+		Name:  "region",
+		Table: map[ft.Value]map[ft.Class]float64{"north": north},
+	}
+	fs := []ft.Feature{
+		{Name: "color", Value: "white"},
+		{Name: "region", Value: "north"},
+	}
+
+	t.Run("lookup", func(t *testing.T) {
+		res, err := lookup.Priors("south", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, south, res)
+		res, err = lookup.Priors("east", nil)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+
+	t.Run("feature lookup", func(t *testing.T) {
+		res, err := features.Priors("", fs)
+		assert.Nil(t, err)
+		assert.Equal(t, north, res)
+		res, err = features.Priors("", fs[:1])
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		p := prior.Fallback{lookup, features, prior.Training{}}
+		res, err := p.Priors("south", fs)
+		assert.Nil(t, err)
+		assert.Equal(t, south, res)
+		res, err = p.Priors("east", fs)
+		assert.Nil(t, err)
+		assert.Equal(t, north, res)
+		res, err = p.Priors("east", fs[:1])
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+
+		p = prior.Fallback{failing{}, lookup}
+		_, err = p.Priors("south", fs)
+		assert.EqualError(t, err, "no connection")
+	})
+}
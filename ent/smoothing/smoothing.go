@@ -0,0 +1,165 @@
+// package smoothing contains algorithms that estimate probabilities of
+// features from their counts. Smoothing allows to give non-zero
+// probabilities to features that never occurred with a class in the
+// training data.
+package smoothing
+
+import (
+	"fmt"
+	"math"
+)
+
+// Counts contain the data needed to estimate a probability of a feature.
+type Counts struct {
+	// Count is the number of cases that have the feature.
+	Count float64
+
+	// Total is the number of all cases. If there are no cases, all values
+	// of the feature's name are equally probable.
+	Total float64
+
+	// Values is the number of known values of the feature's name.
+	Values int
+
+	// Seen is the number of values of the feature's name that occurred
+	// at least once in the cases.
+	Seen int
+}
+
+// seen returns the number of seen values. It is never less than 1.
+func (c Counts) seen() float64 {
+	return math.Max(float64(c.Seen), 1)
+}
+
+// unseen returns the number of values of a feature's name that never
+// occurred in the cases. It is never less than 1.
+func (c Counts) unseen() float64 {
+	return math.Max(float64(c.Values-c.Seen), 1)
+}
+
+// uniform returns the probability of a value when there are no cases.
+func (c Counts) uniform() float64 {
+	return 1 / math.Max(float64(c.Values), 1)
+}
+
+// Smoothing estimates a probability of a feature from its counts.
+type Smoothing interface {
+	// Name is the name of the algorithm. It is used to save the algorithm
+	// in a dump.
+	Name() string
+
+	// Param is the parameter of the algorithm, for example alpha of
+	// Lidstone smoothing. It is 0 for algorithms without parameters.
+	Param() float64
+
+	// Prob returns an estimated probability of a feature.
+	Prob(Counts) float64
+}
+
+// New creates a Smoothing from its name and parameter. Empty name
+// corresponds to the Crude smoothing.
+func New(name string, param float64) (Smoothing, error) {
+	switch name {
+	case "", Crude{}.Name():
+		return Crude{}, nil
+	case Laplace{}.Name():
+		return Laplace{}, nil
+	case Lidstone{}.Name():
+		if param <= 0 {
+			return nil, fmt.Errorf("alpha must be positive, got %g", param)
+		}
+		return Lidstone{Alpha: param}, nil
+	case AbsoluteDiscounting{}.Name():
+		if param <= 0 || param >= 1 {
+			return nil, fmt.Errorf("discount must be between 0 and 1, got %g", param)
+		}
+		return AbsoluteDiscounting{Discount: param}, nil
+	case WittenBell{}.Name():
+		return WittenBell{}, nil
+	default:
+		return nil, fmt.Errorf("unknown smoothing '%s'", name)
+	}
+}
+
+// Crude replaces zero counts with 1. It was the only smoothing before
+// the other algorithms were introduced and it stays the default one.
+type Crude struct{}
+
+func (Crude) Name() string   { return "crude" }
+func (Crude) Param() float64 { return 0 }
+
+func (Crude) Prob(c Counts) float64 {
+	if c.Total <= 0 {
+		return c.uniform()
+	}
+	count := c.Count
+	if count <= 0 {
+		count = 1
+	}
+	return count / c.Total
+}
+
+// Laplace adds one case to counts of every value of a feature's name.
+type Laplace struct{}
+
+func (Laplace) Name() string   { return "laplace" }
+func (Laplace) Param() float64 { return 0 }
+
+func (Laplace) Prob(c Counts) float64 {
+	return Lidstone{Alpha: 1}.Prob(c)
+}
+
+// Lidstone adds Alpha cases to counts of every value of a feature's name.
+// With Alpha equal to 1 it is the same as Laplace smoothing.
+type Lidstone struct {
+	Alpha float64
+}
+
+func (Lidstone) Name() string     { return "lidstone" }
+func (l Lidstone) Param() float64 { return l.Alpha }
+
+func (l Lidstone) Prob(c Counts) float64 {
+	if c.Total <= 0 {
+		return c.uniform()
+	}
+	return (c.Count + l.Alpha) / (c.Total + l.Alpha*float64(c.Values))
+}
+
+// AbsoluteDiscounting subtracts Discount from counts of seen values and
+// redistributes the collected probability mass among values that were not
+// seen. Values with counts smaller than Discount are treated as not seen.
+type AbsoluteDiscounting struct {
+	Discount float64
+}
+
+func (AbsoluteDiscounting) Name() string     { return "absolute-discounting" }
+func (a AbsoluteDiscounting) Param() float64 { return a.Discount }
+
+func (a AbsoluteDiscounting) Prob(c Counts) float64 {
+	if c.Total <= 0 {
+		return c.uniform()
+	}
+	if c.Count > a.Discount {
+		return (c.Count - a.Discount) / c.Total
+	}
+	return a.Discount * c.seen() / (c.Total * c.unseen())
+}
+
+// WittenBell estimates the probability of not seen values from the number
+// of distinct seen values. The more values were seen, the more likely it is
+// to meet a new one.
+type WittenBell struct{}
+
+func (WittenBell) Name() string   { return "witten-bell" }
+func (WittenBell) Param() float64 { return 0 }
+
+func (WittenBell) Prob(c Counts) float64 {
+	if c.Total <= 0 {
+		return c.uniform()
+	}
+	seen := c.seen()
+	if c.Count > 0 {
+		return c.Count / (c.Total + seen)
+	}
+	return seen / ((c.Total + seen) * c.unseen())
+}
@@ -0,0 +1,92 @@
+package smoothing_test
+
+import (
+	"testing"
+
+	"github.com/gnames/bayes/ent/smoothing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProb(t *testing.T) {
+	seen := smoothing.Counts{Count: 30, Total: 40, Values: 3, Seen: 2}
+	unseen := smoothing.Counts{Count: 0, Total: 40, Values: 3, Seen: 2}
+
+	tests := []struct {
+		msg          string
+		s            smoothing.Smoothing
+		seen, unseen float64
+	}{
+		{"crude", smoothing.Crude{}, 0.75, 0.025},
+		{"laplace", smoothing.Laplace{}, 31.0 / 43, 1.0 / 43},
+		{"lidstone", smoothing.Lidstone{Alpha: 0.5}, 30.5 / 41.5, 0.5 / 41.5},
+		{"discounting", smoothing.AbsoluteDiscounting{Discount: 0.5},
+			29.5 / 40, 0.5 * 2 / 40},
+		{"witten-bell", smoothing.WittenBell{}, 30.0 / 42, 2.0 / 42},
+	}
+
+	for _, v := range tests {
+		t.Run(v.msg, func(t *testing.T) {
+			assert.InDelta(t, v.seen, v.s.Prob(seen), 1e-9)
+			assert.InDelta(t, v.unseen, v.s.Prob(unseen), 1e-9)
+
+			// without cases all values are equally probable.
+			empty := smoothing.Counts{Values: 4}
+			assert.InDelta(t, 0.25, v.s.Prob(empty), 1e-9)
+		})
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	// Counts of 3 values of a feature name, the third value was never
+	// seen. Probabilities of all values should sum up to 1.
+	counts := []float64{30, 10, 0}
+	ss := []smoothing.Smoothing{
+		smoothing.Laplace{},
+		smoothing.Lidstone{Alpha: 0.1},
+		smoothing.AbsoluteDiscounting{Discount: 0.7},
+		smoothing.WittenBell{},
+	}
+	for _, s := range ss {
+		var sum float64
+		for _, c := range counts {
+			sum += s.Prob(smoothing.Counts{
+				Count: c, Total: 40, Values: 3, Seen: 2,
+			})
+		}
+		assert.InDelta(t, 1.0, sum, 1e-9, s.Name())
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		param float64
+		res   smoothing.Smoothing
+		err   string
+	}{
+		{"", 0, smoothing.Crude{}, ""},
+		{"crude", 0, smoothing.Crude{}, ""},
+		{"laplace", 0, smoothing.Laplace{}, ""},
+		{"lidstone", 0.3, smoothing.Lidstone{Alpha: 0.3}, ""},
+		{"lidstone", 0, nil, "alpha must be positive, got 0"},
+		{"absolute-discounting", 0.5,
+			smoothing.AbsoluteDiscounting{Discount: 0.5}, ""},
+		{"absolute-discounting", 2, nil,
+			"discount must be between 0 and 1, got 2"},
+		{"witten-bell", 0, smoothing.WittenBell{}, ""},
+		{"good-luck", 0, nil, "unknown smoothing 'good-luck'"},
+	}
+
+	for _, v := range tests {
+		res, err := smoothing.New(v.name, v.param)
+		if v.err != "" {
+			assert.EqualError(t, err, v.err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, v.res, res)
+		res2, err := smoothing.New(res.Name(), res.Param())
+		assert.Nil(t, err)
+		assert.Equal(t, res, res2)
+	}
+}
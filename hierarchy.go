@@ -0,0 +1,80 @@
+package bayes
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+
+	ft "github.com/gnames/bayes/ent/feature"
+)
+
+// checkParents returns an error if a class is its own ancestor.
+func checkParents(parents map[ft.Class]ft.Class) error {
+	for class := range parents {
+		seen := map[ft.Class]struct{}{class: {}}
+		for c := parents[class]; c != ""; c = parents[c] {
+			if _, ok := seen[c]; ok {
+				return fmt.Errorf("cycle in hierarchy of class '%s'", class)
+			}
+			seen[c] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// nodeProbs returns probabilities of all classes of the hierarchy. The
+// probability of a class is the sum of its own probability and
+// probabilities of all its descendants.
+func (nb *bayes) nodeProbs(
+	probs map[ft.Class]float64,
+) map[ft.Class]float64 {
+	// all classes of the hierarchy get probabilities, even if they are
+	// not trained.
+	res := make(map[ft.Class]float64)
+	for class, parent := range nb.parents {
+		res[class] = 0
+		if parent != "" {
+			res[parent] = 0
+		}
+	}
+	for _, class := range nb.classes {
+		p := probs[class]
+		res[class] += p
+		for c := nb.parents[class]; c != ""; c = nb.parents[c] {
+			res[c] += p
+		}
+	}
+	return res
+}
+
+// topDown goes from the root of the hierarchy to the children with the
+// highest probabilities, while the probabilities are not less than the
+// threshold. It returns the path of the classes it passed.
+func (nb *bayes) topDown(
+	probs map[ft.Class]float64,
+	threshold float64,
+) []ft.Class {
+	children := make(map[ft.Class][]ft.Class)
+	for _, class := range slices.Sorted(maps.Keys(probs)) {
+		parent := nb.parents[class]
+		children[parent] = append(children[parent], class)
+	}
+
+	var res []ft.Class
+	level := children[""]
+	for len(level) > 0 {
+		best := slices.MaxFunc(level, func(a, b ft.Class) int {
+			if c := cmp.Compare(probs[a], probs[b]); c != 0 {
+				return c
+			}
+			return cmp.Compare(b, a)
+		})
+		if probs[best] < threshold {
+			break
+		}
+		res = append(res, best)
+		level = children[best]
+	}
+	return res
+}
@@ -0,0 +1,107 @@
+package bayes_test
+
+import (
+	"testing"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHierarchy(t *testing.T) {
+	parents := map[ft.Class]ft.Class{
+		"Jar1":   "Shelf1",
+		"Jar2":   "Shelf1",
+		"Jar3":   "Shelf2",
+		"Shelf1": "Kitchen",
+		"Shelf2": "Kitchen",
+	}
+	chocolate := []ft.Feature{{Name: "CookieF", Value: "chocolate"}}
+
+	t.Run("aggregates probabilities", func(t *testing.T) {
+		nb := bayes.New(bayes.OptParents(parents))
+		err := nb.Train(threeCookieJarsFeatures())
+		assert.Nil(t, err)
+
+		// joint probabilities of Jar1, Jar2 and Jar3 are proportional to
+		// 10, 15 and 40.
+		p, err := nb.PosteriorOdds(chocolate)
+		assert.Nil(t, err)
+		assert.InDelta(t, 40.0/65, p.NodeProbs["Jar3"], 1e-9)
+		assert.InDelta(t, 25.0/65, p.NodeProbs["Shelf1"], 1e-9)
+		assert.InDelta(t, 40.0/65, p.NodeProbs["Shelf2"], 1e-9)
+		assert.InDelta(t, 1.0, p.NodeProbs["Kitchen"], 1e-9)
+		assert.Equal(t, []ft.Class{"Kitchen", "Shelf2", "Jar3"}, p.Path)
+
+		p, err = nb.PosteriorOdds(chocolate, bayes.OptPathThreshold(0.7))
+		assert.Nil(t, err)
+		assert.Equal(t, []ft.Class{"Kitchen"}, p.Path)
+	})
+
+	t.Run("goes top down", func(t *testing.T) {
+		nb := bayes.New(bayes.OptParents(map[ft.Class]ft.Class{
+			"A1": "A", "A2": "A", "B1": "B",
+		}))
+		x := ft.Feature{Name: "x", Value: "true"}
+		lfs := []ft.ClassFeatures{
+			{Class: "A1", Features: []ft.Feature{x}, Weight: ft.Weight(3)},
+			{Class: "A2", Features: []ft.Feature{x}, Weight: ft.Weight(3)},
+			{Class: "B1", Features: []ft.Feature{x}, Weight: ft.Weight(4)},
+		}
+		nb.Train(lfs)
+
+		// B1 is the most probable class, but A1 and A2 together are more
+		// probable than B1.
+
+		p, err := nb.PosteriorOdds([]ft.Feature{x})
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("B1"), p.MaxClass)
+		assert.Equal(t, []ft.Class{"A", "A1"}, p.Path)
+	})
+
+	t.Run("has no path without hierarchy", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(threeCookieJarsFeatures())
+		p, err := nb.PosteriorOdds(chocolate)
+		assert.Nil(t, err)
+		assert.Nil(t, p.Path)
+		assert.Nil(t, p.NodeProbs)
+	})
+
+	t.Run("saves hierarchy", func(t *testing.T) {
+		nb := bayes.New(bayes.OptParents(parents))
+		nb.Train(threeCookieJarsFeatures())
+		dump, err := nb.Dump()
+		assert.Nil(t, err)
+		nb2 := bayes.New()
+		err = nb2.Load(dump)
+		assert.Nil(t, err)
+		assert.Equal(t, "Kitchen", nb2.Inspect().Parents["Shelf1"])
+		p, err := nb2.PosteriorOdds(chocolate)
+		assert.Nil(t, err)
+		assert.Equal(t, []ft.Class{"Kitchen", "Shelf2", "Jar3"}, p.Path)
+	})
+
+	t.Run("finds cycles", func(t *testing.T) {
+		nb := bayes.New(bayes.OptParents(map[ft.Class]ft.Class{
+			"Jar1": "Shelf1", "Shelf1": "Jar1",
+		}))
+		err := nb.Train(threeCookieJarsFeatures())
+		assert.ErrorContains(t, err, "cycle in hierarchy of class")
+		_, err = nb.PosteriorOdds(chocolate)
+		assert.ErrorContains(t, err, "cycle in hierarchy of class")
+
+		err = nb.Load([]byte(`{"parents": {"A": "B", "B": "A"}}`))
+		assert.ErrorContains(t, err, "cycle in hierarchy of class")
+
+		// a valid dump replaces the wrong hierarchy.
+		nb2 := bayes.New()
+		nb2.Train(threeCookieJarsFeatures())
+		dump, err := nb2.Dump()
+		assert.Nil(t, err)
+		err = nb.Load(dump)
+		assert.Nil(t, err)
+		_, err = nb.PosteriorOdds(chocolate)
+		assert.Nil(t, err)
+	})
+}
@@ -1,7 +1,11 @@
 package bayes
 
 import (
+	"context"
+	"iter"
+
 	"github.com/gnames/bayes/ent/bayesdump"
+	"github.com/gnames/bayes/ent/calibration"
 	ft "github.com/gnames/bayes/ent/feature"
 	"github.com/gnames/bayes/ent/posterior"
 )
@@ -9,7 +13,19 @@ import (
 // Trainer interface provides methods for training Bayes object to
 // data from the training set.
 type Trainer interface {
-	Train([]ft.ClassFeatures)
+	// Train adds classified feature sets to the training data.
+	Train([]ft.ClassFeatures) error
+	// TrainContext is the same as Train, but it can be cancelled by the
+	// context.
+	TrainContext(context.Context, []ft.ClassFeatures) error
+	// Untrain removes previously trained feature sets from the training
+	// data.
+	Untrain([]ft.ClassFeatures) error
+	// Calibrate fits a calibration of probabilities of classes to a
+	// validation set.
+	Calibrate(
+		calibration.Method, []ft.ClassFeatures, ...Option,
+	) error
 }
 
 // Serializer provides methods for dumping data from Bayes object to
@@ -21,6 +37,9 @@ type Serializer interface {
 	// Load takes a slice of bytes that corresponds to output.Output and
 	// creates a Bayes instance from it.
 	Load([]byte) error
+	// LoadContext is the same as Load, but it can be cancelled by the
+	// context.
+	LoadContext(context.Context, []byte) error
 	// Dump takes an internal data of a Bayes instance, converts it to
 	// object.Object and serializes it to slice of bytes.
 	Dump() ([]byte, error)
@@ -34,10 +53,42 @@ type Calc interface {
 	// PosteriorOdds uses set of features to determing which class they belong
 	// to with the most probability.
 	PosteriorOdds([]ft.Feature, ...Option) (posterior.Odds, error)
+	// Odds returns posterior odds of the class of a feature set given the
+	// features of the set.
+	Odds(ft.ClassFeatures, ...Option) (float64, error)
+	// PosteriorOddsBatch classifies many sets of features concurrently and
+	// returns results in the order of the input.
+	PosteriorOddsBatch([][]ft.Feature, ...Option) []BatchResult
+	// PosteriorOddsBatchContext is the same as PosteriorOddsBatch, but it
+	// can be cancelled by the context.
+	PosteriorOddsBatchContext(
+		context.Context, [][]ft.Feature, ...Option,
+	) ([]BatchResult, error)
+	// PosteriorOddsSeq classifies a stream of sets of features concurrently
+	// and yields results in the order of the input.
+	PosteriorOddsSeq(
+		iter.Seq[[]ft.Feature], ...Option,
+	) iter.Seq2[posterior.Odds, error]
+	// PosteriorOddsSeqContext is the same as PosteriorOddsSeq, but it can be
+	// cancelled by the context.
+	PosteriorOddsSeqContext(
+		context.Context, iter.Seq[[]ft.Feature], ...Option,
+	) iter.Seq2[posterior.Odds, error]
 	// Likelihood gives an isolated likelihood of a feature.
 	Likelihood(ft.Feature, ft.Class) (float64, error)
 }
 
+// PriorProfiler manages named prior profiles that are saved together with
+// the training data.
+type PriorProfiler interface {
+	// AddPriorProfile saves numbers of cases of classes under a name.
+	AddPriorProfile(string, map[ft.Class]float64) error
+	// PriorProfiles returns names of saved prior profiles.
+	PriorProfiles() []string
+	// DeletePriorProfile removes a prior profile.
+	DeletePriorProfile(string) error
+}
+
 // Bayes interface uses Bayes algorithm for calculation of the posterior and
 // prior odds. For training it takes manually curated data packed into
 // features, and allows to serialize and deserialize the data.
@@ -45,4 +96,24 @@ type Bayes interface {
 	Trainer
 	Serializer
 	Calc
+	PriorProfiler
+}
+
+// MultiLabel interface classifies feature sets that can belong to several
+// classes at once. Such classes are called labels. Presence of every label
+// is decided by a separate model.
+type MultiLabel interface {
+	// Train adds feature sets with their labels to the training data.
+	Train([]ft.MultiClassFeatures) error
+	// Labels returns all known labels.
+	Labels() []ft.Class
+	// PosteriorOdds calculates odds of presence of every label for a set
+	// of features and selects labels that pass the threshold.
+	PosteriorOdds([]ft.Feature, ...Option) (posterior.Labels, error)
+	// Inspect returns training data of all models.
+	Inspect() bayesdump.MultiLabelDump
+	// Load replaces training data with the data from a dump.
+	Load([]byte) error
+	// Dump serializes training data to a slice of bytes.
+	Dump() ([]byte, error)
 }
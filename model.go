@@ -0,0 +1,45 @@
+package bayes
+
+import "fmt"
+
+// Model is a kind of Naive Bayes model. It defines how features are
+// counted during training and how their probabilities are estimated.
+type Model string
+
+const (
+	// Categorical is the default model. Probabilities of features are
+	// estimated from the number of feature sets of a class. Every
+	// occurrence of a feature is counted, so feature sets should not
+	// repeat features, use Multinomial model for frequencies.
+	Categorical Model = "categorical"
+
+	// Multinomial is the model for features with frequencies, for example
+	// words of a text. Every occurrence of a feature in a feature set is
+	// counted, so repeated features express frequencies of the features.
+	// Probabilities of features are estimated from the number of all
+	// occurrences of features with the same name in a class.
+	Multinomial Model = "multinomial"
+
+	// Complement is Complement Naive Bayes model for classes with very
+	// different numbers of cases. Features are counted as by Multinomial
+	// model, but probabilities of features are estimated from all classes
+	// except the given one, which gives more data for estimation for small
+	// classes. Weights of features are normalized, so classes with many
+	// features do not dominate. The model calculates scores of classes
+	// instead of odds and probabilities.
+	Complement Model = "complement"
+)
+
+// NewModel converts a string to a Model. Empty string means Categorical
+// model.
+func NewModel(s string) (Model, error) {
+	m := Model(s)
+	switch m {
+	case "":
+		return Categorical, nil
+	case Categorical, Multinomial, Complement:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown model '%s'", s)
+	}
+}
@@ -0,0 +1,299 @@
+package bayes
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/gnames/bayes/ent/bayesdump"
+	ft "github.com/gnames/bayes/ent/feature"
+	pst "github.com/gnames/bayes/ent/posterior"
+)
+
+const (
+	// allClass is the only class of the model with all training data.
+	allClass ft.Class = "all"
+
+	// present is the class of feature sets that have a label.
+	present ft.Class = "present"
+
+	// absent is the class of feature sets that do not have a label.
+	absent ft.Class = "absent"
+)
+
+type multiLabel struct {
+	// mu protects models from concurrent changes during classification.
+	mu sync.RWMutex
+
+	// opts are options for creation of models.
+	opts []ModelOption
+
+	// labels are known labels in the order of their first appearance.
+	labels []ft.Class
+
+	// all contains all training data as one class. It is used to create
+	// a model of a new label, because all previous feature sets did not
+	// have the label.
+	all *bayes
+
+	// models decide presence of every label.
+	models map[ft.Class]*bayes
+}
+
+// NewMultiLabel creates a new multi-label classifier. Every label gets a
+// model created with the options, that decides if the label is present or
+// absent.
+func NewMultiLabel(opts ...ModelOption) MultiLabel {
+	return &multiLabel{
+		opts:   opts,
+		all:    New(opts...).(*bayes),
+		models: make(map[ft.Class]*bayes),
+	}
+}
+
+// Train adds feature sets with their labels to the training data. Labels
+// that appear for the first time are absent from all previous feature
+// sets.
+func (ml *multiLabel) Train(lfs []ft.MultiClassFeatures) error {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	all := make([]ft.ClassFeatures, len(lfs))
+	var labels []ft.Class
+	for i, lf := range lfs {
+		all[i] = ft.ClassFeatures{
+			Class:    allClass,
+			Features: lf.Features,
+			Weight:   lf.Weight,
+		}
+		// feature sets with zero weight do not introduce labels.
+		if lf.CaseWeight() == 0 {
+			continue
+		}
+		for _, l := range lf.Classes {
+			if _, ok := ml.models[l]; !ok && !slices.Contains(labels, l) {
+				labels = append(labels, l)
+			}
+		}
+	}
+
+	models := make(map[ft.Class]*bayes, len(labels))
+	for _, l := range labels {
+		m, err := ml.absentModel()
+		if err != nil {
+			return err
+		}
+		models[l] = m
+	}
+
+	// training data are validated by the first model, so other models do
+	// not fail.
+	if err := ml.all.Train(all); err != nil {
+		return err
+	}
+	maps.Copy(ml.models, models)
+	ml.labels = append(ml.labels, labels...)
+
+	for _, l := range ml.labels {
+		if err := ml.models[l].Train(labelFeatures(l, lfs)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// absentModel creates a model where all previous training data do not
+// have a label.
+func (ml *multiLabel) absentModel() (*bayes, error) {
+	dump := ml.all.Inspect()
+	for i, v := range dump.Classes {
+		if v == string(allClass) {
+			dump.Classes[i] = string(absent)
+		}
+	}
+	dump.ClassCases = renameClass(dump.ClassCases)
+	for _, fv := range dump.FeatureCases {
+		for value, cv := range fv {
+			fv[value] = renameClass(cv)
+		}
+	}
+	for name, n := range dump.Numeric {
+		n.ClassStats = renameClass(n.ClassStats)
+		dump.Numeric[name] = n
+	}
+
+	res := New(ml.opts...).(*bayes)
+	if err := res.loadDump(context.Background(), dump); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// renameClass renames allClass to absent class.
+func renameClass[T any](m map[string]T) map[string]T {
+	if v, ok := m[string(allClass)]; ok {
+		delete(m, string(allClass))
+		m[string(absent)] = v
+	}
+	return m
+}
+
+// labelFeatures converts feature sets to the feature sets of presence or
+// absence of a label.
+func labelFeatures(
+	label ft.Class,
+	lfs []ft.MultiClassFeatures,
+) []ft.ClassFeatures {
+	res := make([]ft.ClassFeatures, len(lfs))
+	for i, lf := range lfs {
+		class := absent
+		if slices.Contains(lf.Classes, label) {
+			class = present
+		}
+		res[i] = ft.ClassFeatures{
+			Class:    class,
+			Features: lf.Features,
+			Weight:   lf.Weight,
+		}
+	}
+	return res
+}
+
+// Labels returns all known labels in the order of their first appearance.
+func (ml *multiLabel) Labels() []ft.Class {
+	ml.mu.RLock()
+	defer ml.mu.RUnlock()
+	return slices.Clone(ml.labels)
+}
+
+// PosteriorOdds calculates odds of presence of every label. Labels with
+// probabilities not less than the threshold set by OptThreshold are
+// selected. Options are applied to the model of every label, so
+// OptPriorOdds has to use 'present' and 'absent' classes.
+func (ml *multiLabel) PosteriorOdds(
+	fs []ft.Feature,
+	opts ...Option,
+) (pst.Labels, error) {
+	ml.mu.RLock()
+	defer ml.mu.RUnlock()
+
+	cfg := applyOptions(opts)
+	res := pst.Labels{
+		LabelOdds:  make(map[ft.Class]float64, len(ml.labels)),
+		LogOdds:    make(map[ft.Class]float64, len(ml.labels)),
+		LabelProbs: make(map[ft.Class]float64, len(ml.labels)),
+		Threshold:  cfg.threshold,
+	}
+	for _, l := range ml.labels {
+		logOdds, err := ml.models[l].presenceLogOdds(fs, opts)
+		if err != nil {
+			return res, fmt.Errorf("cannot classify label '%s': %w", l, err)
+		}
+		res.LogOdds[l] = logOdds
+		res.LabelOdds[l] = math.Exp(logOdds)
+		res.LabelProbs[l] = pst.LogOddsToProb(logOdds)
+		if res.LabelProbs[l] >= cfg.threshold {
+			res.Labels = append(res.Labels, l)
+		}
+	}
+	slices.SortFunc(res.Labels, func(a, b ft.Class) int {
+		if c := cmp.Compare(res.LogOdds[b], res.LogOdds[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	return res, nil
+}
+
+// presenceLogOdds returns logarithm of odds of presence of a label. If
+// the label is present in all training data, or absent from all of them,
+// the result is infinite.
+func (nb *bayes) presenceLogOdds(
+	fs []ft.Feature,
+	opts []Option,
+) (float64, error) {
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
+
+	if nb.model == Complement {
+		return 0, errors.New("complement model does not calculate odds")
+	}
+	if _, ok := nb.classCases[absent]; !ok {
+		return math.Inf(1), nil
+	}
+	if _, ok := nb.classCases[present]; !ok {
+		return math.Inf(-1), nil
+	}
+	res, err := nb.posteriorOdds(fs, nb.newConfig(opts))
+	if err != nil {
+		return 0, err
+	}
+	return res.LogOdds[present], nil
+}
+
+// Inspect returns training data of all models.
+func (ml *multiLabel) Inspect() bayesdump.MultiLabelDump {
+	ml.mu.RLock()
+	defer ml.mu.RUnlock()
+
+	res := bayesdump.MultiLabelDump{
+		Labels: make([]string, len(ml.labels)),
+		All:    ml.all.Inspect(),
+		Models: make(map[string]bayesdump.BayesDump, len(ml.labels)),
+	}
+	for i, l := range ml.labels {
+		res.Labels[i] = string(l)
+		res.Models[string(l)] = ml.models[l].Inspect()
+	}
+	return res
+}
+
+// Dump serializes training data of all models into a JSON format.
+func (ml *multiLabel) Dump() ([]byte, error) {
+	res := ml.Inspect()
+	return json.MarshalIndent(&res, "", "  ")
+}
+
+// Load replaces training data of all models with the data from a dump.
+func (ml *multiLabel) Load(dump []byte) error {
+	var res bayesdump.MultiLabelDump
+	r := bytes.NewReader(dump)
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	all := New(ml.opts...).(*bayes)
+	if err := all.loadDump(ctx, res.All); err != nil {
+		return err
+	}
+	labels := make([]ft.Class, len(res.Labels))
+	models := make(map[ft.Class]*bayes, len(res.Labels))
+	for i, v := range res.Labels {
+		m := New(ml.opts...).(*bayes)
+		d, ok := res.Models[v]
+		if !ok {
+			return fmt.Errorf("no model for label '%s'", v)
+		}
+		if err := m.loadDump(ctx, d); err != nil {
+			return err
+		}
+		labels[i] = ft.Class(v)
+		models[labels[i]] = m
+	}
+
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	ml.all = all
+	ml.labels = labels
+	ml.models = models
+	return nil
+}
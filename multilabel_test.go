@@ -0,0 +1,128 @@
+package bayes_test
+
+import (
+	"testing"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiLabel(t *testing.T) {
+	t.Run("selects several labels", func(t *testing.T) {
+		ml := bayes.NewMultiLabel()
+		err := ml.Train(animalFeatures())
+		assert.Nil(t, err)
+		assert.Equal(t, []ft.Class{"bird", "water", "fish"}, ml.Labels())
+
+		res, err := ml.PosteriorOdds(traits("feathers", "swims"))
+		assert.Nil(t, err)
+		assert.Equal(t, []ft.Class{"bird", "water"}, res.Labels)
+		assert.Equal(t, 0.5, res.Threshold)
+		assert.Less(t, res.LabelProbs["fish"], 0.5)
+		assert.Greater(t, res.LogOdds["bird"], res.LogOdds["water"])
+
+		res, err = ml.PosteriorOdds(traits("fins"))
+		assert.Nil(t, err)
+		assert.Equal(t, []ft.Class{"fish", "water"}, res.Labels)
+	})
+
+	t.Run("uses threshold", func(t *testing.T) {
+		ml := bayes.NewMultiLabel()
+		ml.Train(animalFeatures())
+		fs := traits("feathers", "swims")
+		res, err := ml.PosteriorOdds(fs, bayes.OptThreshold(0.8))
+		assert.Nil(t, err)
+		assert.Equal(t, []ft.Class{"bird"}, res.Labels)
+		res, err = ml.PosteriorOdds(fs, bayes.OptThreshold(0))
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(res.Labels))
+	})
+
+	t.Run("trains incrementally", func(t *testing.T) {
+		lfs := animalFeatures()
+		ml1 := bayes.NewMultiLabel()
+		ml1.Train(lfs)
+		ml2 := bayes.NewMultiLabel()
+		ml2.Train(lfs[:1])
+		err := ml2.Train(lfs[1:])
+		assert.Nil(t, err)
+
+		o1, o2 := ml1.Inspect(), ml2.Inspect()
+		assert.ElementsMatch(t, o1.Labels, o2.Labels)
+		for _, l := range o1.Labels {
+			m1, m2 := o1.Models[l], o2.Models[l]
+			assert.Equal(t, m1.ClassCases, m2.ClassCases)
+			assert.Equal(t, m1.FeatureCases, m2.FeatureCases)
+		}
+		// the first feature set has no fish label, while the label
+		// appeared only in the second batch.
+		assert.Equal(t, 8.0, o2.Models["fish"].ClassCases["absent"])
+	})
+
+	t.Run("dumps and loads", func(t *testing.T) {
+		ml := bayes.NewMultiLabel()
+		ml.Train(animalFeatures())
+		dump, err := ml.Dump()
+		assert.Nil(t, err)
+		ml2 := bayes.NewMultiLabel()
+		err = ml2.Load(dump)
+		assert.Nil(t, err)
+		assert.Equal(t, ml.Labels(), ml2.Labels())
+
+		fs := traits("feathers", "swims")
+		res1, _ := ml.PosteriorOdds(fs)
+		res2, err := ml2.PosteriorOdds(fs)
+		assert.Nil(t, err)
+		assert.Equal(t, res1, res2)
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		ml := bayes.NewMultiLabel()
+		ml.Train(animalFeatures())
+		_, err := ml.PosteriorOdds(traits("fur"))
+		assert.EqualError(t, err,
+			"cannot classify label 'bird': all features are unknown")
+
+		lfs := animalFeatures()
+		lfs[0].Weight = ft.Weight(-1)
+		err = ml.Train(lfs)
+		assert.ErrorContains(t, err, "invalid weight -1")
+		assert.Equal(t, ml.Inspect().All.CasesTotal, 16.0)
+	})
+}
+
+func traits(ts ...string) []ft.Feature {
+	res := make([]ft.Feature, len(ts))
+	for i, v := range ts {
+		res[i] = ft.Feature{Name: "trait", Value: ft.Value(v)}
+	}
+	return res
+}
+
+// animalFeatures contain traits of animals labeled by their groups and
+// habitats.
+func animalFeatures() []ft.MultiClassFeatures {
+	var res []ft.MultiClassFeatures
+	for range 4 {
+		res = append(res,
+			ft.MultiClassFeatures{
+				Classes:  []ft.Class{"bird"},
+				Features: traits("feathers", "wings"),
+			},
+			ft.MultiClassFeatures{
+				Classes:  []ft.Class{"bird", "water"},
+				Features: traits("feathers", "swims"),
+			},
+			ft.MultiClassFeatures{
+				Classes:  []ft.Class{"fish", "water"},
+				Features: traits("fins", "swims"),
+			},
+			ft.MultiClassFeatures{
+				Classes:  []ft.Class{"fish", "water"},
+				Features: traits("fins", "scales"),
+			},
+		)
+	}
+	return res
+}
@@ -0,0 +1,136 @@
+package bayes_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultinomial(t *testing.T) {
+	buy := ft.Feature{Name: "word", Value: "buy"}
+
+	t.Run("counts frequencies of features", func(t *testing.T) {
+		nb := bayes.New(bayes.OptModel(bayes.Multinomial))
+		err := nb.Train(textFeatures())
+		assert.Nil(t, err)
+		o := nb.Inspect()
+		assert.Equal(t, "multinomial", o.Model)
+		assert.Equal(t, 3.0, o.FeatureCases["word"]["buy"]["spam"])
+
+		// 3 of 5 words in spam are 'buy', there are no 'buy' in 5 words of
+		// ham, which is smoothed to 1.
+		lh, err := nb.Likelihood(buy, "spam")
+		assert.Nil(t, err)
+		assert.InDelta(t, 3.0, lh, 1e-9)
+
+		p, err := nb.PosteriorOdds(words("buy", "buy", "now"))
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("spam"), p.MaxClass)
+		assert.InDelta(t, 2*math.Log(3), p.LogLikelihoods["spam"][buy], 1e-9)
+	})
+
+	t.Run("categorical model counts every feature", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(textFeatures())
+		o := nb.Inspect()
+		assert.Equal(t, "categorical", o.Model)
+		assert.Equal(t, 3.0, o.FeatureCases["word"]["buy"]["spam"])
+
+		p, err := nb.PosteriorOdds(words("buy", "buy"))
+		assert.Nil(t, err)
+		p2, err := nb.PosteriorOdds(words("buy"))
+		assert.Nil(t, err)
+		llh := p2.LogLikelihoods["spam"][buy]
+		assert.Greater(t, llh, 0.0)
+		assert.InDelta(t, 2*llh, p.LogLikelihoods["spam"][buy], 1e-9)
+	})
+
+	t.Run("works with classes without a feature name", func(t *testing.T) {
+		nb := bayes.New(bayes.OptModel(bayes.Multinomial))
+		err := nb.Train([]ft.ClassFeatures{
+			{Class: "A", Features: []ft.Feature{{Name: "w", Value: "a"}}},
+			{Class: "B", Features: []ft.Feature{{Name: "x", Value: "b"}}},
+		})
+		assert.Nil(t, err)
+		p, err := nb.PosteriorOdds([]ft.Feature{{Name: "w", Value: "a"}})
+		assert.Nil(t, err)
+		for _, class := range []ft.Class{"A", "B"} {
+			assert.False(t, math.IsInf(p.LogOdds[class], 0))
+			assert.InDelta(t, 0.5, p.ClassProbs[class], 1e-9)
+		}
+
+		p, err = nb.PosteriorOdds([]ft.Feature{
+			{Name: "w", Value: "a"}, {Name: "x", Value: "b"},
+		})
+		assert.Nil(t, err)
+		assert.InDelta(t, 0.0, p.LogOdds["A"], 1e-9)
+	})
+
+	t.Run("untrains and loads", func(t *testing.T) {
+		lfs := textFeatures()
+		nb := bayes.New(bayes.OptModel(bayes.Multinomial))
+		nb.Train(lfs)
+		err := nb.Untrain(lfs[:1])
+		assert.Nil(t, err)
+
+		nb2 := bayes.New(bayes.OptModel(bayes.Multinomial))
+		nb2.Train(lfs[1:])
+		assertSameTexts(t, nb, nb2)
+
+		dump, err := nb.Dump()
+		assert.Nil(t, err)
+		nb3 := bayes.New()
+		err = nb3.Load(dump)
+		assert.Nil(t, err)
+		assertSameTexts(t, nb, nb3)
+		assert.Equal(t, "multinomial", nb3.Inspect().Model)
+	})
+
+	t.Run("rejects unknown models", func(t *testing.T) {
+		nb := bayes.New(bayes.OptModel("foo"))
+		err := nb.Train(textFeatures())
+		assert.EqualError(t, err, "unknown model 'foo'")
+		_, err = nb.PosteriorOdds(words("buy"))
+		assert.EqualError(t, err, "unknown model 'foo'")
+	})
+
+	t.Run("does not load unknown models", func(t *testing.T) {
+		nb := bayes.New()
+		err := nb.Load([]byte(`{"model": "gaussian"}`))
+		assert.EqualError(t, err, "unknown model 'gaussian'")
+	})
+}
+
+func assertSameTexts(t *testing.T, nb1, nb2 bayes.Bayes) {
+	o1, o2 := nb1.Inspect(), nb2.Inspect()
+	assert.Equal(t, o1.ClassCases, o2.ClassCases)
+	assert.Equal(t, o1.FeatureCases, o2.FeatureCases)
+	for _, f := range words("buy", "now", "meet") {
+		lh1, err1 := nb1.Likelihood(f, "spam")
+		lh2, err2 := nb2.Likelihood(f, "spam")
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.InDelta(t, lh1, lh2, 1e-9)
+	}
+}
+
+func words(ws ...string) []ft.Feature {
+	res := make([]ft.Feature, len(ws))
+	for i, w := range ws {
+		res[i] = ft.Feature{Name: "word", Value: ft.Value(w)}
+	}
+	return res
+}
+
+// textFeatures are words of short texts.
+func textFeatures() []ft.ClassFeatures {
+	return []ft.ClassFeatures{
+		{Class: "spam", Features: words("buy", "buy", "now")},
+		{Class: "spam", Features: words("buy", "cheap")},
+		{Class: "ham", Features: words("meet", "now")},
+		{Class: "ham", Features: words("meet", "lunch", "now")},
+	}
+}
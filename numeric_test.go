@@ -0,0 +1,189 @@
+package bayes_test
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/gnames/bayes"
+	"github.com/gnames/bayes/ent/distribution"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumeric(t *testing.T) {
+	kinds := []distribution.Kind{
+		distribution.Gaussian,
+		distribution.LogNormal,
+		distribution.Poisson,
+		distribution.KDE,
+	}
+
+	for _, kind := range kinds {
+		t.Run(string(kind), func(t *testing.T) {
+			nb := bayes.New(bayes.OptNumeric(kind, "SizeF"))
+			err := nb.Train(cookieSizesFeatures())
+			assert.Nil(t, err)
+			o := nb.Inspect()
+			assert.Equal(t, string(kind), o.Numeric["SizeF"].Distribution)
+			assert.Equal(t, 40.0, o.Numeric["SizeF"].ClassStats["Jar1"].Count)
+			assert.NotContains(t, o.FeatureCases, "SizeF")
+
+			p, err := nb.PosteriorOdds(sizeFeatures("3"))
+			assert.Nil(t, err)
+			assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+			p, err = nb.PosteriorOdds(sizeFeatures("9"))
+			assert.Nil(t, err)
+			assert.Equal(t, ft.Class("Jar2"), p.MaxClass)
+
+			lh, err := nb.Likelihood(sizeFeatures("3")[0], ft.Class("Jar1"))
+			assert.Nil(t, err)
+			assert.Greater(t, lh, 1.0)
+
+			dump, err := nb.Dump()
+			assert.Nil(t, err)
+			nb2 := bayes.New()
+			err = nb2.Load(dump)
+			assert.Nil(t, err)
+			p2, err := nb2.PosteriorOdds(sizeFeatures("9"))
+			assert.Nil(t, err)
+			assert.Equal(t, p.MaxClass, p2.MaxClass)
+			assert.InDelta(t, p.MaxLogOdds, p2.MaxLogOdds, 1e-9)
+		})
+	}
+
+	t.Run("works with categorical features", func(t *testing.T) {
+		nb := bayes.New(bayes.OptNumeric(distribution.Gaussian, "SizeF"))
+		nb.Train(cookieSizesFeatures())
+		fs := []ft.Feature{
+			{Name: "SizeF", Value: "5"},
+			{Name: "ShapeF", Value: "round"},
+		}
+		p, err := nb.PosteriorOdds(fs)
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar2"), p.MaxClass)
+		assert.Equal(t, 2, len(p.LogLikelihoods["Jar1"])-1)
+
+		p, err = nb.PosteriorOdds(fs[:1])
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+	})
+
+	t.Run("works with large values", func(t *testing.T) {
+		lfs := cookieSizesFeatures()
+		for i := range lfs {
+			f := lfs[i].Features[0]
+			x, _ := strconv.ParseFloat(string(f.Value), 64)
+			lfs[i].Features[0].Value = ft.Value(fmt.Sprintf("%.1f", 1e9+x))
+		}
+		nb := bayes.New(bayes.OptNumeric(distribution.Gaussian, "SizeF"))
+		nb.Train(lfs)
+		st := nb.Inspect().Numeric["SizeF"].ClassStats["Jar1"]
+		assert.InDelta(t, 0.5, st.Variance(), 1e-6)
+
+		p, err := nb.PosteriorOdds(sizeFeatures("1000000003"))
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+		p, err = nb.PosteriorOdds(sizeFeatures("1000000008"))
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar2"), p.MaxClass)
+	})
+
+	t.Run("untrains numeric features", func(t *testing.T) {
+		lfs := cookieSizesFeatures()
+		nb := bayes.New(bayes.OptNumeric(distribution.KDE, "SizeF"))
+		nb.Train(lfs)
+		err := nb.Untrain(lfs[10:])
+		assert.Nil(t, err)
+
+		nb2 := bayes.New(bayes.OptNumeric(distribution.KDE, "SizeF"))
+		nb2.Train(lfs[:10])
+		o1, o2 := nb.Inspect(), nb2.Inspect()
+		st1 := o1.Numeric["SizeF"].ClassStats["Jar1"]
+		st2 := o2.Numeric["SizeF"].ClassStats["Jar1"]
+		assert.InDelta(t, st2.Mean(), st1.Mean(), 1e-9)
+		assert.Equal(t, st2.Samples, st1.Samples)
+		assert.NotContains(t, o1.Numeric["SizeF"].ClassStats, "Jar2")
+
+		lf := lfs[0]
+		lf.Features = append([]ft.Feature{{Name: "SizeF", Value: "100"}},
+			lf.Features[1:]...)
+		err = nb.Untrain([]ft.ClassFeatures{lf})
+		assert.EqualError(t, err,
+			"cannot remove numeric feature 'SizeF' from class 'Jar1': "+
+				"cannot remove 1 values of 100, there are only 0")
+	})
+
+	t.Run("checks numeric values", func(t *testing.T) {
+		nb := bayes.New(bayes.OptNumeric(distribution.LogNormal, "SizeF"))
+		lfs := cookieSizesFeatures()
+		lfs[3].Features[0].Value = "big"
+		err := nb.Train(lfs)
+		assert.ErrorContains(t, err,
+			"invalid value 'big' of numeric feature 'SizeF'")
+
+		lfs[3].Features[0].Value = "-1"
+		err = nb.Train(lfs)
+		assert.EqualError(t, err, "invalid value '-1' of numeric feature "+
+			"'SizeF': value -1 is not positive")
+		assert.Equal(t, 0.0, nb.Inspect().CasesTotal)
+
+		nb.Train(cookieSizesFeatures())
+		_, err = nb.PosteriorOdds(sizeFeatures("small"))
+		assert.ErrorContains(t, err,
+			"invalid value 'small' of numeric feature 'SizeF'")
+	})
+
+	t.Run("rejects unknown distributions", func(t *testing.T) {
+		nb := bayes.New(bayes.OptNumeric("weird", "SizeF"))
+		err := nb.Train(cookieSizesFeatures())
+		assert.EqualError(t, err, "unknown distribution 'weird'")
+		_, err = nb.PosteriorOdds(sizeFeatures("3"))
+		assert.EqualError(t, err, "unknown distribution 'weird'")
+	})
+
+	t.Run("smooths variance of a class with one value", func(t *testing.T) {
+		kinds := []distribution.Kind{
+			distribution.Gaussian, distribution.LogNormal, distribution.KDE,
+		}
+		for _, kind := range kinds {
+			nb := bayes.New(bayes.OptNumeric(kind, "SizeF"))
+			err := nb.Train([]ft.ClassFeatures{
+				{Class: "A", Features: sizeFeatures("1")},
+				{Class: "B", Features: sizeFeatures("10")},
+			})
+			assert.Nil(t, err)
+			p, err := nb.PosteriorOdds(sizeFeatures("3"))
+			assert.Nil(t, err)
+			assert.Equal(t, ft.Class("A"), p.MaxClass, kind)
+			assert.Less(t, math.Abs(p.LogOdds["A"]), 1000.0, kind)
+			assert.Greater(t, p.ClassProbs["B"], 0.0, kind)
+		}
+	})
+
+	t.Run("does not load unknown distributions", func(t *testing.T) {
+		nb := bayes.New()
+		err := nb.Load([]byte(`{"numeric": {"SizeF": {"distribution": "flat"}}}`))
+		assert.EqualError(t, err, "unknown distribution 'flat'")
+	})
+}
+
+func sizeFeatures(size string) []ft.Feature {
+	return []ft.Feature{{Name: "SizeF", Value: ft.Value(size)}}
+}
+
+// cookieSizesFeatures adds sizes to cookies. Cookies in Jar1 are smaller
+// than cookies in Jar2.
+func cookieSizesFeatures() []ft.ClassFeatures {
+	lfs := cookieJarsFeatures()
+	for i := range lfs {
+		size := 2 + float64(i%5)*0.5
+		if lfs[i].Class == "Jar2" {
+			size = 7 + float64(i%7)*0.5
+		}
+		f := ft.Feature{Name: "SizeF", Value: ft.Value(fmt.Sprintf("%g", size))}
+		lfs[i].Features = append([]ft.Feature{f}, lfs[i].Features...)
+	}
+	return lfs
+}
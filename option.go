@@ -0,0 +1,389 @@
+package bayes
+
+import (
+	"fmt"
+
+	"github.com/gnames/bayes/ent/discretize"
+	"github.com/gnames/bayes/ent/distribution"
+	ft "github.com/gnames/bayes/ent/feature"
+	pst "github.com/gnames/bayes/ent/posterior"
+	"github.com/gnames/bayes/ent/prior"
+	"github.com/gnames/bayes/ent/smoothing"
+)
+
+// ModelOption sets up a Bayes object during its creation.
+type ModelOption func(nb *bayes)
+
+// OptModel sets the kind of Naive Bayes model. By default the Categorical
+// model is used. Training and classification return an error for an
+// unknown model.
+func OptModel(m Model) ModelOption {
+	return func(nb *bayes) {
+		nb.model = m
+	}
+}
+
+// OptPriorProvider sets a provider of prior probabilities of classes. The
+// provider gets features of every classified entity and a key from
+// OptPriorKey, so prior probabilities can depend on a region, a source of
+// data etc. Priors set by options of a classification call take
+// precedence over the provider. The provider is not saved in dumps.
+func OptPriorProvider(p prior.Provider) ModelOption {
+	return func(nb *bayes) {
+		nb.priors = p
+	}
+}
+
+// OptSmoothing sets an algorithm for estimation of probabilities of
+// features. By default the smoothing.Crude algorithm is used. Training
+// and classification return an error if the algorithm or its parameter
+// is not valid.
+func OptSmoothing(s smoothing.Smoothing) ModelOption {
+	return func(nb *bayes) {
+		nb.smoothing = s
+	}
+}
+
+// OptNumeric declares features with the given names as numeric. Values of
+// numeric features are numbers, and every class learns a distribution of
+// the kind for them, instead of counting every value separately. Training
+// and classification return an error for an unknown kind.
+func OptNumeric(kind distribution.Kind, names ...ft.Name) ModelOption {
+	return func(nb *bayes) {
+		for _, name := range names {
+			nb.numeric[name] = kind
+			delete(nb.binning, name)
+		}
+	}
+}
+
+// OptBinning declares features with the given names as numeric features
+// that are converted to categorical ones. Boundaries of bins are learned
+// by the method from the first training batch that contains the feature,
+// and stay the same afterwards. Values of such features are replaced by
+// labels of their bins during training and classification.
+// Number of bins is ignored by discretize.MDL method, unless it is
+// positive, in which case it limits the number of bins. Other methods need
+// at least 2 bins, otherwise training and classification return an error.
+func OptBinning(
+	method discretize.Method,
+	bins int,
+	names ...ft.Name,
+) ModelOption {
+	return func(nb *bayes) {
+		for _, name := range names {
+			nb.binning[name] = discretize.Binning{Method: method, Bins: bins}
+			delete(nb.numeric, name)
+		}
+	}
+}
+
+// OptBernoulli declares features with the given names as binary ones.
+// Every known value of such a feature is either present in a feature set
+// or absent from it, and absent values are used as evidence as well as
+// present ones. Repeated features with these names are counted once by
+// all models.
+func OptBernoulli(names ...ft.Name) ModelOption {
+	return func(nb *bayes) {
+		for _, name := range names {
+			nb.bernoulli[name] = struct{}{}
+		}
+	}
+}
+
+// OptParents declares a hierarchy of classes, for example a taxonomy of
+// families, genera and species. The map contains parents of classes,
+// classes without parents are at the top of the hierarchy. Parents do not
+// have to be trained classes. Results of classification get probabilities
+// of all classes of the hierarchy and the path from the top of the
+// hierarchy to the most probable class. The hierarchy is validated once,
+// training and classification return an error if it has a cycle.
+func OptParents(parents map[ft.Class]ft.Class) ModelOption {
+	return func(nb *bayes) {
+		for class, parent := range parents {
+			nb.parents[class] = parent
+		}
+	}
+}
+
+// config contains settings of one classification call. Every call gets
+// its own config, so concurrent calls with different options do not
+// affect each other.
+type config struct {
+	// classCases are cases of classes for calculation of prior odds.
+	classCases map[ft.Class]float64
+
+	// casesTotal is the total of classCases.
+	casesTotal float64
+
+	// counts are numbers of cases of classes reported in results. They
+	// are cases from training with prior counts of options. In contrast
+	// to classCases they are not replaced by probabilities.
+	counts map[ft.Class]float64
+
+	// priorCounts override numbers of cases of some classes.
+	priorCounts map[ft.Class]float64
+
+	// priorProbs override prior probabilities of some classes.
+	priorProbs map[ft.Class]float64
+
+	// priorProfile is the name of a saved prior profile.
+	priorProfile string
+
+	// priorKey is given to the provider of prior probabilities.
+	priorKey string
+
+	// err is an error in the options. Classification returns it instead
+	// of results.
+	err error
+
+	// ignorePriorOdds indicates that likelihood will be calculated without
+	// taking in account prior odds.
+	ignorePriorOdds bool
+
+	// logOnly indicates that results will not be converted from log space.
+	logOnly bool
+
+	// workers is the number of concurrent workers for batch
+	// classification.
+	workers int
+
+	// threshold is the minimal probability of a label in multi-label
+	// classification.
+	threshold float64
+
+	// pathThreshold is the minimal probability of a class in the path of
+	// hierarchical classification.
+	pathThreshold float64
+
+	// minPosterior is the minimal probability of the best class.
+	minPosterior float64
+
+	// minMargin is the minimal difference between logarithms of odds of
+	// the best and the second best classes.
+	minMargin float64
+
+	// minKnownFeatures is the minimal number of known features.
+	minKnownFeatures int
+
+	// topK is the number of top classes in the ranking of results.
+	topK int
+
+	// unknown is the policy for features that are not known from
+	// training.
+	unknown UnknownPolicy
+
+	// costs are costs of misclassification.
+	costs pst.Costs
+
+	// uncalibrated skips calibration of probabilities of classes.
+	uncalibrated bool
+}
+
+// newConfig creates a config of a classification call from the options.
+// It has to be called while the training data are locked.
+func (nb *bayes) newConfig(opts []Option) config {
+	cfg := applyOptions(opts)
+	cfg.classCases = nb.classCases
+	cfg.casesTotal = nb.casesTotal
+	cfg.counts = nb.classCases
+	if cfg.err == nil {
+		cfg.err = nb.err
+	}
+	if cfg.err == nil {
+		cfg.err = nb.checkComplement(cfg)
+	}
+	if cfg.err == nil {
+		cfg.err = nb.profileCounts(&cfg)
+	}
+	if cfg.err == nil && cfg.costs != nil {
+		cfg.err = nb.checkCosts(cfg.costs)
+	}
+	if cfg.err == nil && (cfg.priorCounts != nil || cfg.priorProbs != nil) {
+		cfg.err = nb.overridePriors(&cfg)
+	}
+	return cfg
+}
+
+// applyOptions creates a config from the options without using training
+// data.
+func applyOptions(opts []Option) config {
+	cfg := config{threshold: 0.5}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Option changes settings of a classification call.
+type Option func(cfg *config)
+
+// OptPriorOdds allows dynamical change of prior odds used in calculations.
+// Sometimes prior odds during classification event are very different from
+// ones aquired during training. If for example 'real' prior odds are 100 times
+// larger it means the calculated posterior odds will be 100 times smaller than
+// what they would suppose to be.
+// The map contains numbers of cases of classes. Classes that are not in the
+// map keep their numbers of cases from training.
+func OptPriorOdds(lc map[ft.Class]int) Option {
+	return func(cfg *config) {
+		cfg.priorCounts = make(map[ft.Class]float64, len(lc))
+		for k, v := range lc {
+			cfg.priorCounts[k] = float64(v)
+		}
+	}
+}
+
+// OptPriorProbs overrides prior probabilities of some classes. Classes that
+// are not in the map share the rest of the probability proportionally to
+// their training data. If all classes are in the map, their probabilities
+// are normalized to sum up to 1. Probabilities of 0 or 1 are smoothed, so
+// no class gets infinite prior odds. Invalid probabilities, unknown classes
+// or probabilities that sum up to more than 1 make classification return
+// an error.
+func OptPriorProbs(probs map[ft.Class]float64) Option {
+	return func(cfg *config) {
+		cfg.priorProbs = make(map[ft.Class]float64, len(probs))
+		for k, v := range probs {
+			cfg.priorProbs[k] = v
+		}
+	}
+}
+
+// OptPriorClassOdds is the same as OptPriorProbs, but prior probabilities
+// are given as odds of classes against all other classes.
+func OptPriorClassOdds(odds map[ft.Class]float64) Option {
+	return func(cfg *config) {
+		cfg.priorProbs = make(map[ft.Class]float64, len(odds))
+		for k, v := range odds {
+			if !(v >= 0) && cfg.err == nil {
+				cfg.err = fmt.Errorf("prior odds of class '%s' must not be "+
+					"negative, got %g", k, v)
+			}
+			cfg.priorProbs[k] = pst.OddsToProb(v)
+		}
+	}
+}
+
+// OptIgnorePriorOdds might be needed if it is a muV
+// PriorOdds already are accounted for.
+func OptIgnorePriorOdds(b bool) Option {
+	return func(cfg *config) {
+		cfg.ignorePriorOdds = b
+	}
+}
+
+// OptLogOnly allows to skip exponentiation of the results. In this case
+// only LogOdds, MaxLogOdds and LogLikelihoods of the results are set.
+// The rest can be calculated later by posterior.Odds.Exp method.
+func OptLogOnly(b bool) Option {
+	return func(cfg *config) {
+		cfg.logOnly = b
+	}
+}
+
+// OptWorkers sets the number of concurrent workers for batch
+// classification. By default the number of workers is the same as the
+// number of CPUs.
+func OptWorkers(n int) Option {
+	return func(cfg *config) {
+		cfg.workers = n
+	}
+}
+
+// OptThreshold sets the minimal probability of a label that is selected by
+// multi-label classification. By default the threshold is 0.5.
+func OptThreshold(p float64) Option {
+	return func(cfg *config) {
+		cfg.threshold = p
+	}
+}
+
+// OptPathThreshold sets the minimal probability of classes in the path of
+// hierarchical classification. Top-down classification stops at the
+// deepest class that has the probability not less than the threshold. By
+// default the path goes to the most probable class of the lowest level.
+func OptPathThreshold(p float64) Option {
+	return func(cfg *config) {
+		cfg.pathThreshold = p
+	}
+}
+
+// OptMinPosterior sets the minimal probability of the best class in
+// ClassProbs. If the probability is lower, classification abstains.
+func OptMinPosterior(p float64) Option {
+	return func(cfg *config) {
+		cfg.minPosterior = p
+	}
+}
+
+// OptMinMargin sets the minimal difference between natural logarithms of
+// odds of the best and the second best classes. If the difference is
+// smaller, classification abstains.
+func OptMinMargin(m float64) Option {
+	return func(cfg *config) {
+		cfg.minMargin = m
+	}
+}
+
+// OptMinKnownFeatures sets the minimal number of features that are known
+// from training. If there are fewer known features, classification
+// abstains.
+func OptMinKnownFeatures(n int) Option {
+	return func(cfg *config) {
+		cfg.minKnownFeatures = n
+	}
+}
+
+// OptTopK limits the ranking of classes in results to k classes with the
+// best odds. By default the ranking contains all classes.
+func OptTopK(k int) Option {
+	return func(cfg *config) {
+		cfg.topK = k
+	}
+}
+
+// OptUnknown sets the policy for features that are not known from
+// training. By default unknown features are skipped.
+func OptUnknown(p UnknownPolicy) Option {
+	return func(cfg *config) {
+		cfg.unknown = p
+	}
+}
+
+// OptPriorKey sets a key for the provider of prior probabilities, for
+// example a name of a region or a source of data.
+func OptPriorKey(key string) Option {
+	return func(cfg *config) {
+		cfg.priorKey = key
+	}
+}
+
+// OptPriorProfile selects a prior profile saved by AddPriorProfile. Its
+// numbers of cases of classes are used the same way as the ones of
+// OptPriorOdds, which take precedence over the profile. Unknown profile
+// makes classification return an error.
+func OptPriorProfile(name string) Option {
+	return func(cfg *config) {
+		cfg.priorProfile = name
+	}
+}
+
+// OptCosts sets costs of misclassification. Results then contain expected
+// costs of deciding for each class and the class with the minimal
+// expected cost. Costs[actual][decided] is the cost of deciding for a
+// class when the entity belongs to the actual class. Missing costs are 0
+// for correct decisions and 1 for wrong ones.
+func OptCosts(costs pst.Costs) Option {
+	return func(cfg *config) {
+		cfg.costs = costs
+	}
+}
+
+// OptUncalibrated skips calibration of probabilities of classes set by
+// Calibrate.
+func OptUncalibrated(b bool) Option {
+	return func(cfg *config) {
+		cfg.uncalibrated = b
+	}
+}
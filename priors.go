@@ -0,0 +1,164 @@
+package bayes
+
+import (
+	"fmt"
+	"maps"
+	"math"
+
+	ft "github.com/gnames/bayes/ent/feature"
+)
+
+// priorEpsilon is added to probabilities of degenerate priors, so no class
+// gets zero or full prior probability.
+const priorEpsilon = 1e-6
+
+// providePriors sets prior probabilities from the provider of priors, if
+// they are not set by options.
+func (nb *bayes) providePriors(fs []ft.Feature, cfg *config) error {
+	if nb.priors == nil || cfg.priorCounts != nil || cfg.priorProbs != nil {
+		return nil
+	}
+	probs, err := nb.priors.Priors(cfg.priorKey, fs)
+	if err != nil {
+		return fmt.Errorf("cannot get prior probabilities: %w", err)
+	}
+	if probs == nil {
+		return nil
+	}
+	cfg.priorProbs = probs
+	return nb.overridePriors(cfg)
+}
+
+// overridePriors replaces prior probabilities of classes from training
+// with the ones from options. Classes that are not overridden share the
+// rest of the probability proportionally to their training data.
+func (nb *bayes) overridePriors(cfg *config) error {
+	if nb.casesTotal <= 0 {
+		return nil
+	}
+	cases := nb.classCases
+	if cfg.priorCounts != nil {
+		for class := range cfg.priorCounts {
+			if err := nb.checkPriorClass(class); err != nil {
+				return err
+			}
+		}
+		if err := checkPriorCounts(cfg.priorCounts); err != nil {
+			return err
+		}
+		cases = maps.Clone(nb.classCases)
+		maps.Copy(cases, cfg.priorCounts)
+	}
+
+	var total float64
+	for _, class := range nb.classes {
+		total += cases[class]
+	}
+	if total <= 0 {
+		return fmt.Errorf("prior counts of all classes are zero")
+	}
+	probs := make(map[ft.Class]float64, len(nb.classes))
+	for _, class := range nb.classes {
+		probs[class] = cases[class] / total
+	}
+
+	if cfg.priorProbs != nil {
+		if err := nb.overrideProbs(probs, cfg.priorProbs); err != nil {
+			return err
+		}
+	}
+	smoothPriors(probs)
+
+	cfg.counts = cases
+	cfg.classCases = probs
+	cfg.casesTotal = 1
+	return nil
+}
+
+// overrideProbs sets probabilities of some classes and renormalizes the
+// rest of them. If all classes are set, their probabilities are
+// normalized.
+func (nb *bayes) overrideProbs(
+	probs map[ft.Class]float64,
+	priors map[ft.Class]float64,
+) error {
+	var fixed float64
+	for class, p := range priors {
+		if err := nb.checkPriorClass(class); err != nil {
+			return err
+		}
+		if !(p >= 0 && p <= 1) {
+			return fmt.Errorf("prior probability of class '%s' must be "+
+				"between 0 and 1, got %g", class, p)
+		}
+		fixed += p
+	}
+	if fixed > 1+epsilon {
+		return fmt.Errorf(
+			"prior probabilities sum up to %g, more than 1", fixed,
+		)
+	}
+
+	var rest float64
+	for class, p := range probs {
+		if _, ok := priors[class]; !ok {
+			rest += p
+		}
+	}
+	if rest <= 0 {
+		if fixed <= 0 {
+			return fmt.Errorf("prior probabilities of all classes are zero")
+		}
+		for class := range probs {
+			probs[class] = priors[class] / fixed
+		}
+		return nil
+	}
+
+	for class, p := range probs {
+		if v, ok := priors[class]; ok {
+			probs[class] = v
+			continue
+		}
+		probs[class] = p / rest * max(1-fixed, 0)
+	}
+	return nil
+}
+
+// checkPriorCounts returns an error if a number of cases of a class is
+// negative or not finite.
+func checkPriorCounts(counts map[ft.Class]float64) error {
+	for class, v := range counts {
+		if v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return fmt.Errorf("prior count of class '%s' must be "+
+				"a non-negative number, got %g", class, v)
+		}
+	}
+	return nil
+}
+
+func (nb *bayes) checkPriorClass(class ft.Class) error {
+	if _, ok := nb.classCases[class]; !ok {
+		return fmt.Errorf("unknown class '%s' in prior odds", class)
+	}
+	return nil
+}
+
+// smoothPriors makes sure that no class has zero or full probability,
+// which would make prior odds infinite.
+func smoothPriors(probs map[ft.Class]float64) {
+	var degenerate bool
+	for _, p := range probs {
+		if p <= 0 || p >= 1 {
+			degenerate = true
+			break
+		}
+	}
+	if !degenerate {
+		return
+	}
+	k := float64(len(probs))
+	for class, p := range probs {
+		probs[class] = (p + priorEpsilon) / (1 + k*priorEpsilon)
+	}
+}
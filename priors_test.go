@@ -0,0 +1,149 @@
+package bayes_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	pst "github.com/gnames/bayes/ent/posterior"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriors(t *testing.T) {
+	nb := bayes.New()
+	nb.Train(threeCookieJarsFeatures())
+	fs := []ft.Feature{{Name: "CookieF", Value: "chocolate"}}
+	noPriors, err := nb.PosteriorOdds(fs, bayes.OptIgnorePriorOdds(true))
+	assert.Nil(t, err)
+
+	// priorProbs returns prior probabilities used by the classification.
+	priorProbs := func(p pst.Odds) map[ft.Class]float64 {
+		res := make(map[ft.Class]float64)
+		for class, v := range p.LogOdds {
+			res[class] = pst.LogOddsToProb(v - noPriors.LogOdds[class])
+		}
+		return res
+	}
+
+	// Jar1 has 40 cases, Jar2 has 30 cases, Jar3 has 40 cases.
+	tests := []struct {
+		msg string
+		opt bayes.Option
+		exp map[ft.Class]float64
+	}{
+		{
+			"partial probabilities",
+			bayes.OptPriorProbs(map[ft.Class]float64{"Jar1": 0.5}),
+			map[ft.Class]float64{
+				"Jar1": 0.5, "Jar2": 15.0 / 70, "Jar3": 20.0 / 70,
+			},
+		},
+		{
+			"partial odds",
+			bayes.OptPriorClassOdds(map[ft.Class]float64{"Jar1": 1}),
+			map[ft.Class]float64{
+				"Jar1": 0.5, "Jar2": 15.0 / 70, "Jar3": 20.0 / 70,
+			},
+		},
+		{
+			"partial counts",
+			bayes.OptPriorOdds(map[ft.Class]int{"Jar1": 70}),
+			map[ft.Class]float64{
+				"Jar1": 0.5, "Jar2": 30.0 / 140, "Jar3": 40.0 / 140,
+			},
+		},
+		{
+			"normalized probabilities",
+			bayes.OptPriorProbs(map[ft.Class]float64{
+				"Jar1": 0.2, "Jar2": 0.2, "Jar3": 0.2,
+			}),
+			map[ft.Class]float64{
+				"Jar1": 1.0 / 3, "Jar2": 1.0 / 3, "Jar3": 1.0 / 3,
+			},
+		},
+	}
+	for _, v := range tests {
+		t.Run(v.msg, func(t *testing.T) {
+			p, err := nb.PosteriorOdds(fs, v.opt)
+			assert.Nil(t, err)
+			res := priorProbs(p)
+			for class, exp := range v.exp {
+				assert.InDelta(t, exp, res[class], 1e-9)
+				assert.InDelta(t, exp, p.PriorProbs[class], 1e-9)
+			}
+		})
+	}
+
+	t.Run("keeps numbers of cases", func(t *testing.T) {
+		opt := bayes.OptPriorProbs(map[ft.Class]float64{"Jar1": 0.5})
+		p, err := nb.PosteriorOdds(fs, opt)
+		assert.Nil(t, err)
+		exp := pst.ClassCases{"Jar1": 40, "Jar2": 30, "Jar3": 40}
+		assert.Equal(t, exp, p.ClassCases)
+
+		opt = bayes.OptPriorOdds(map[ft.Class]int{"Jar1": 70})
+		p, err = nb.PosteriorOdds(fs, opt)
+		assert.Nil(t, err)
+		exp = pst.ClassCases{"Jar1": 70, "Jar2": 30, "Jar3": 40}
+		assert.Equal(t, exp, p.ClassCases)
+
+		assert.Nil(t, noPriors.PriorProbs)
+	})
+
+	t.Run("smooths degenerate priors", func(t *testing.T) {
+		opt := bayes.OptPriorProbs(map[ft.Class]float64{"Jar2": 1})
+		p, err := nb.PosteriorOdds(fs, opt)
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar2"), p.MaxClass)
+		assert.False(t, math.IsInf(p.LogOdds["Jar2"], 0))
+		assert.False(t, math.IsInf(p.LogOdds["Jar1"], 0))
+
+		opt = bayes.OptPriorOdds(map[ft.Class]int{"Jar1": 0, "Jar3": 0})
+		p, err = nb.PosteriorOdds(fs, opt)
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar2"), p.MaxClass)
+	})
+
+	t.Run("validates priors", func(t *testing.T) {
+		tests := []struct {
+			opt bayes.Option
+			err string
+		}{
+			{
+				bayes.OptPriorProbs(map[ft.Class]float64{"Jar4": 0.5}),
+				"unknown class 'Jar4' in prior odds",
+			},
+			{
+				bayes.OptPriorProbs(map[ft.Class]float64{"Jar1": 1.5}),
+				"prior probability of class 'Jar1' must be between 0 and 1, " +
+					"got 1.5",
+			},
+			{
+				bayes.OptPriorProbs(
+					map[ft.Class]float64{"Jar1": 0.6, "Jar2": 0.6},
+				),
+				"prior probabilities sum up to 1.2, more than 1",
+			},
+			{
+				bayes.OptPriorClassOdds(map[ft.Class]float64{"Jar1": -1}),
+				"prior odds of class 'Jar1' must not be negative, got -1",
+			},
+			{
+				bayes.OptPriorOdds(map[ft.Class]int{"Jar1": -1}),
+				"prior count of class 'Jar1' must be a non-negative number, " +
+					"got -1",
+			},
+			{
+				bayes.OptPriorOdds(
+					map[ft.Class]int{"Jar1": 0, "Jar2": 0, "Jar3": 0},
+				),
+				"prior counts of all classes are zero",
+			},
+		}
+		for _, v := range tests {
+			_, err := nb.PosteriorOdds(fs, v.opt)
+			assert.EqualError(t, err, v.err)
+		}
+	})
+}
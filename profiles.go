@@ -0,0 +1,81 @@
+package bayes
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	ft "github.com/gnames/bayes/ent/feature"
+)
+
+// AddPriorProfile saves numbers of cases of classes under a name. Such
+// numbers are usually real-world frequencies of classes for a source of
+// data or a region. Classes that are not in the profile keep their
+// numbers of cases from training. A profile with the same name is
+// replaced. Profiles are saved in dumps and are selected for
+// classification by OptPriorProfile. Numbers of cases are validated even
+// before training, classes of the profile are validated after it.
+func (nb *bayes) AddPriorProfile(
+	name string,
+	counts map[ft.Class]float64,
+) error {
+	if name == "" {
+		return errors.New("name of prior profile is empty")
+	}
+	if len(counts) == 0 {
+		return fmt.Errorf("cannot add prior profile '%s': "+
+			"there are no numbers of cases", name)
+	}
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+
+	err := checkPriorCounts(counts)
+	if err == nil {
+		cfg := config{priorCounts: counts}
+		err = nb.overridePriors(&cfg)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot add prior profile '%s': %w", name, err)
+	}
+	nb.profiles[name] = maps.Clone(counts)
+	return nil
+}
+
+// PriorProfiles returns sorted names of prior profiles.
+func (nb *bayes) PriorProfiles() []string {
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(nb.profiles))
+}
+
+// DeletePriorProfile removes a prior profile.
+func (nb *bayes) DeletePriorProfile(name string) error {
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+
+	if _, ok := nb.profiles[name]; !ok {
+		return fmt.Errorf("unknown prior profile '%s'", name)
+	}
+	delete(nb.profiles, name)
+	return nil
+}
+
+// profileCounts adds numbers of cases of the prior profile selected by
+// options to the config. Numbers of cases set by OptPriorOdds take
+// precedence over the profile.
+func (nb *bayes) profileCounts(cfg *config) error {
+	if cfg.priorProfile == "" {
+		return nil
+	}
+	counts, ok := nb.profiles[cfg.priorProfile]
+	if !ok {
+		return fmt.Errorf("unknown prior profile '%s'", cfg.priorProfile)
+	}
+	res := make(map[ft.Class]float64, len(counts)+len(cfg.priorCounts))
+	maps.Copy(res, counts)
+	maps.Copy(res, cfg.priorCounts)
+	cfg.priorCounts = res
+	return nil
+}
@@ -0,0 +1,115 @@
+package bayes_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorProfiles(t *testing.T) {
+	plain := []ft.Feature{{Name: "CookieF", Value: "plain"}}
+
+	t.Run("manages profiles", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		err := nb.AddPriorProfile("shop", map[ft.Class]float64{"Jar2": 120})
+		assert.Nil(t, err)
+		err = nb.AddPriorProfile("home", map[ft.Class]float64{"Jar1": 10})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"home", "shop"}, nb.PriorProfiles())
+
+		err = nb.DeletePriorProfile("home")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"shop"}, nb.PriorProfiles())
+		err = nb.DeletePriorProfile("home")
+		assert.EqualError(t, err, "unknown prior profile 'home'")
+	})
+
+	t.Run("validates profiles", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		err := nb.AddPriorProfile("", map[ft.Class]float64{"Jar2": 1})
+		assert.EqualError(t, err, "name of prior profile is empty")
+		err = nb.AddPriorProfile("shop", map[ft.Class]float64{"Jar5": 1})
+		assert.EqualError(t, err, "cannot add prior profile 'shop': "+
+			"unknown class 'Jar5' in prior odds")
+		err = nb.AddPriorProfile("shop", map[ft.Class]float64{"Jar2": -1})
+		assert.EqualError(t, err, "cannot add prior profile 'shop': "+
+			"prior count of class 'Jar2' must be a non-negative number, got -1")
+		err = nb.AddPriorProfile("shop", nil)
+		assert.EqualError(t, err, "cannot add prior profile 'shop': "+
+			"there are no numbers of cases")
+		assert.Empty(t, nb.PriorProfiles())
+	})
+
+	t.Run("validates profiles before training", func(t *testing.T) {
+		nb := bayes.New()
+		inf := math.Inf(1)
+		err := nb.AddPriorProfile("shop", map[ft.Class]float64{"Jar2": inf})
+		assert.EqualError(t, err, "cannot add prior profile 'shop': "+
+			"prior count of class 'Jar2' must be a non-negative number, "+
+			"got +Inf")
+		err = nb.AddPriorProfile("shop", map[ft.Class]float64{"Jar2": 120})
+		assert.Nil(t, err)
+
+		nb.Train(cookieJarsFeatures())
+		p, err := nb.PosteriorOdds(plain, bayes.OptPriorProfile("shop"))
+		assert.Nil(t, err)
+		assert.InDelta(t, 2.0, p.MaxOdds, 1e-6)
+	})
+
+	t.Run("does not load invalid profiles", func(t *testing.T) {
+		nb := bayes.New()
+		err := nb.Load([]byte(`{"priorProfiles": {"shop": null}}`))
+		assert.EqualError(t, err,
+			"prior profile 'shop' has no numbers of cases")
+		err = nb.Load([]byte(`{"priorProfiles": {"shop": {"Jar2": -1}}}`))
+		assert.EqualError(t, err, "cannot load prior profile 'shop': "+
+			"prior count of class 'Jar2' must be a non-negative number, got -1")
+	})
+
+	t.Run("classifies with profiles", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		nb.AddPriorProfile("shop", map[ft.Class]float64{"Jar2": 120})
+
+		p, err := nb.PosteriorOdds(plain)
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+
+		// prior odds of Jar2 are 120/40, its likelihood is 2/3.
+		p, err = nb.PosteriorOdds(plain, bayes.OptPriorProfile("shop"))
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar2"), p.MaxClass)
+		assert.InDelta(t, 2.0, p.MaxOdds, 1e-6)
+
+		// numbers of cases of the call take precedence.
+		opt := bayes.OptPriorOdds(map[ft.Class]int{"Jar2": 30})
+		p, err = nb.PosteriorOdds(plain, bayes.OptPriorProfile("shop"), opt)
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+
+		_, err = nb.PosteriorOdds(plain, bayes.OptPriorProfile("home"))
+		assert.EqualError(t, err, "unknown prior profile 'home'")
+	})
+
+	t.Run("saves profiles in dumps", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		nb.AddPriorProfile("shop", map[ft.Class]float64{"Jar2": 120})
+		dump, err := nb.Dump()
+		assert.Nil(t, err)
+		assert.Equal(t, 120.0, nb.Inspect().PriorProfiles["shop"]["Jar2"])
+
+		nb2 := bayes.New()
+		err = nb2.Load(dump)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"shop"}, nb2.PriorProfiles())
+		p, err := nb2.PosteriorOdds(plain, bayes.OptPriorProfile("shop"))
+		assert.Nil(t, err)
+		assert.InDelta(t, 2.0, p.MaxOdds, 1e-6)
+	})
+}
@@ -0,0 +1,75 @@
+package bayes_test
+
+import (
+	"testing"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/gnames/bayes/ent/prior"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorProvider(t *testing.T) {
+	regions := prior.Lookup{
+		"jar2 shelf": {"Jar2": 0.9},
+	}
+	plain := []ft.Feature{{Name: "CookieF", Value: "plain"}}
+
+	t.Run("uses priors by key", func(t *testing.T) {
+		nb := bayes.New(bayes.OptPriorProvider(regions))
+		nb.Train(cookieJarsFeatures())
+
+		p, err := nb.PosteriorOdds(plain)
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar1"), p.MaxClass)
+
+		// likelihood of Jar2 is 2/3, prior odds are 9.
+		p, err = nb.PosteriorOdds(plain, bayes.OptPriorKey("jar2 shelf"))
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar2"), p.MaxClass)
+		assert.InDelta(t, 6.0, p.MaxOdds, 1e-6)
+
+		// options of the call take precedence
+		opt := bayes.OptPriorProbs(map[ft.Class]float64{"Jar2": 0.5})
+		p, err = nb.PosteriorOdds(plain, bayes.OptPriorKey("jar2 shelf"), opt)
+		assert.Nil(t, err)
+		assert.InDelta(t, 2.0/3, p.ClassOdds["Jar2"], 1e-9)
+	})
+
+	t.Run("uses priors by features", func(t *testing.T) {
+		nb := bayes.New(bayes.OptPriorProvider(prior.FeatureLookup{
+			Name: "ShelfF",
+			Table: map[ft.Value]map[ft.Class]float64{
+				"top": {"Jar1": 0.1},
+			},
+		}))
+		nb.Train(cookieJarsFeatures())
+		fs := append(plain, ft.Feature{Name: "ShelfF", Value: "top"})
+		res := nb.PosteriorOddsBatch([][]ft.Feature{plain, fs})
+		assert.Nil(t, res[0].Err)
+		assert.Equal(t, ft.Class("Jar1"), res[0].Odds.MaxClass)
+		assert.Nil(t, res[1].Err)
+		assert.Equal(t, ft.Class("Jar2"), res[1].Odds.MaxClass)
+	})
+
+	t.Run("keeps provider after load", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		dump, _ := nb.Dump()
+		nb2 := bayes.New(bayes.OptPriorProvider(regions))
+		err := nb2.Load(dump)
+		assert.Nil(t, err)
+		p, err := nb2.PosteriorOdds(plain, bayes.OptPriorKey("jar2 shelf"))
+		assert.Nil(t, err)
+		assert.Equal(t, ft.Class("Jar2"), p.MaxClass)
+	})
+
+	t.Run("validates priors", func(t *testing.T) {
+		nb := bayes.New(bayes.OptPriorProvider(prior.Lookup{
+			"wrong": {"Jar5": 0.5},
+		}))
+		nb.Train(cookieJarsFeatures())
+		_, err := nb.PosteriorOdds(plain, bayes.OptPriorKey("wrong"))
+		assert.EqualError(t, err, "unknown class 'Jar5' in prior odds")
+	})
+}
@@ -0,0 +1,85 @@
+package bayes_test
+
+import (
+	"testing"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	pst "github.com/gnames/bayes/ent/posterior"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRanking(t *testing.T) {
+	nb := bayes.New()
+	nb.Train(threeCookieJarsFeatures())
+	fs := []ft.Feature{{Name: "CookieF", Value: "chocolate"}}
+
+	p, err := nb.PosteriorOdds(fs)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(p.Ranking))
+	assert.Equal(t, p.MaxClass, p.Ranking[0].Class)
+	assert.Equal(t, p.MaxOdds, p.Ranking[0].Odds)
+	assert.Equal(t, p.ClassProbs[p.MaxClass], p.Ranking[0].Prob)
+	for i := 1; i < len(p.Ranking); i++ {
+		assert.GreaterOrEqual(t, p.Ranking[i-1].LogOdds, p.Ranking[i].LogOdds)
+	}
+	second := p.Ranking[1]
+	assert.InDelta(t, p.MaxLogOdds-second.LogOdds, p.Margin, 1e-9)
+
+	p2, err := nb.PosteriorOdds(fs, bayes.OptTopK(1))
+	assert.Nil(t, err)
+	assert.Equal(t, p.Ranking[:1], p2.Ranking)
+	assert.Equal(t, p.Margin, p2.Margin)
+}
+
+func TestRankingAgreesWithProbs(t *testing.T) {
+	// One-vs-rest odds prefer C, while probabilities of classes calculated
+	// from joint probabilities of features would prefer B.
+	sets := []struct {
+		class ft.Class
+		f, g  ft.Value
+	}{
+		{"A", "z", "z"}, {"A", "z", "z"}, {"B", "x", "y"},
+		{"C", "y", "x"}, {"C", "y", "x"}, {"C", "y", "x"},
+		{"C", "y", "z"}, {"C", "z", "x"},
+	}
+	var lfs []ft.ClassFeatures
+	for _, v := range sets {
+		lfs = append(lfs, ft.ClassFeatures{
+			Class: v.class,
+			Features: []ft.Feature{
+				{Name: "f", Value: v.f}, {Name: "g", Value: v.g},
+			},
+		})
+	}
+	nb := bayes.New()
+	err := nb.Train(lfs)
+	assert.Nil(t, err)
+
+	fs := []ft.Feature{{Name: "f", Value: "y"}, {Name: "g", Value: "y"}}
+	p, err := nb.PosteriorOdds(fs, bayes.OptCosts(pst.Costs{}))
+	assert.Nil(t, err)
+	assert.Equal(t, ft.Class("C"), p.MaxClass)
+	assert.Equal(t, ft.Class("C"), p.MinCostClass)
+	var classes []ft.Class
+	for i, v := range p.Ranking {
+		classes = append(classes, v.Class)
+		if i > 0 {
+			assert.Greater(t, p.Ranking[i-1].Prob, v.Prob)
+		}
+	}
+	assert.Equal(t, []ft.Class{"C", "B", "A"}, classes)
+
+	// probabilities of classes are normalized probabilities from odds.
+	var sum float64
+	for _, v := range p.LogOdds {
+		sum += pst.LogOddsToProb(v)
+	}
+	for class, v := range p.LogOdds {
+		assert.InDelta(t, pst.LogOddsToProb(v)/sum, p.ClassProbs[class], 1e-9)
+	}
+
+	p, err = nb.PosteriorOdds(fs, bayes.OptMinPosterior(0.39))
+	assert.Nil(t, err)
+	assert.False(t, p.Abstained)
+}
@@ -1,30 +1,446 @@
 package bayes
 
 import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/gnames/bayes/ent/discretize"
+	"github.com/gnames/bayes/ent/distribution"
 	ft "github.com/gnames/bayes/ent/feature"
 )
 
-func (nb *bayes) Train(lfs []ft.ClassFeatures) {
+// epsilon is the precision for comparing weighted counts. Counts that are
+// smaller than epsilon are treated as zero.
+const epsilon = 1e-9
+
+// counts contains aggregated data from a batch of classified feature sets.
+type counts struct {
+	// classes are the classes of the batch in the order of their first
+	// appearance.
+	classes []ft.Class
+
+	// casesTotal is the weighted number of entries in the batch.
+	casesTotal float64
+
+	// classCases is the weighted number of entries per class in the batch.
+	classCases map[ft.Class]float64
+
+	// featureCases is the weighted number of entries per feature and class
+	// in the batch.
+	featureCases map[ft.Feature]map[ft.Class]float64
+
+	// numStats are statistics of values of numeric features per class in
+	// the batch.
+	numStats map[ft.Name]map[ft.Class]distribution.Stats
+
+	// boundaries are boundaries of bins that were learned from the batch.
+	boundaries map[ft.Name][]float64
+}
+
+// settings define how feature sets are counted.
+type settings struct {
+	// version is the version of the settings in the Bayes object.
+	version int
+
+	// model is the kind of Naive Bayes model.
+	model Model
+
+	// numeric are names of numeric features with kinds of their
+	// distributions.
+	numeric map[ft.Name]distribution.Kind
+
+	// binning are names of numeric features that are converted to bins.
+	binning map[ft.Name]discretize.Binning
+
+	// bernoulli are names of binary features.
+	bernoulli map[ft.Name]struct{}
+
+	// boundaries are already learned boundaries of bins.
+	boundaries map[ft.Name][]float64
+}
+
+// settings returns current settings of counting. Maps of the settings are
+// never changed, they can only be replaced, so it is safe to use them
+// without a lock.
+func (nb *bayes) settings() settings {
+	return settings{
+		version:    nb.version,
+		model:      nb.model,
+		numeric:    nb.numeric,
+		binning:    nb.binning,
+		bernoulli:  nb.bernoulli,
+		boundaries: nb.boundaries,
+	}
+}
+
+// newCounts aggregates a batch of feature sets. If learn is true,
+// boundaries of bins that are not known yet are learned from the batch.
+// Otherwise such features cause an error.
+func newCounts(
+	ctx context.Context,
+	lfs []ft.ClassFeatures,
+	st settings,
+	learn bool,
+) (counts, error) {
+	res := counts{
+		classCases:   make(map[ft.Class]float64),
+		featureCases: make(map[ft.Feature]map[ft.Class]float64),
+		numStats:     make(map[ft.Name]map[ft.Class]distribution.Stats),
+	}
+	bounds := st.boundaries
+	if learn {
+		var err error
+		res.boundaries, err = learnBoundaries(ctx, lfs, st)
+		if err != nil {
+			return res, err
+		}
+		if len(res.boundaries) > 0 {
+			bounds = maps.Clone(bounds)
+			maps.Copy(bounds, res.boundaries)
+		}
+	}
+	numeric := st.numeric
+	// seen are binary features of a feature set that are already
+	// counted.
+	seen := make(map[ft.Feature]struct{})
+	for i := range lfs {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		class := lfs[i].Class
+		w := lfs[i].CaseWeight()
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return res, fmt.Errorf("invalid weight %g for class '%s'", w, class)
+		}
+		if w == 0 {
+			continue
+		}
+		if _, ok := res.classCases[class]; !ok {
+			res.classes = append(res.classes, class)
+		}
+		res.classCases[class] += w
+		res.casesTotal += w
+		clear(seen)
+		for _, f := range lfs[i].Features {
+			if _, ok := st.binning[f.Name]; ok {
+				x, err := parseBinned(f)
+				if err != nil {
+					return res, err
+				}
+				b, ok := bounds[f.Name]
+				if !ok {
+					return res, fmt.Errorf("no bins for feature '%s'", f.Name)
+				}
+				f.Value = discretize.Label(b, x)
+			}
+			if kind, ok := numeric[f.Name]; ok {
+				x, err := parseNumeric(kind, f)
+				if err != nil {
+					return res, err
+				}
+				if _, ok = res.numStats[f.Name]; !ok {
+					res.numStats[f.Name] = make(map[ft.Class]distribution.Stats)
+				}
+				st := distribution.NewStats(kind, x, w)
+				res.numStats[f.Name][class] = res.numStats[f.Name][class].Add(st)
+				continue
+			}
+			if _, ok := st.bernoulli[f.Name]; ok {
+				if _, ok := seen[f]; ok {
+					continue
+				}
+				seen[f] = struct{}{}
+			}
+			if _, ok := res.featureCases[f]; !ok {
+				res.featureCases[f] = make(map[ft.Class]float64)
+			}
+			res.featureCases[f][class] += w
+		}
+	}
+	return res, nil
+}
+
+// learnBoundaries learns boundaries of bins for features that do not have
+// them yet.
+func learnBoundaries(
+	ctx context.Context,
+	lfs []ft.ClassFeatures,
+	st settings,
+) (map[ft.Name][]float64, error) {
+	if len(st.binning) == 0 {
+		return nil, nil
+	}
+	vals := make(map[ft.Name][]discretize.Value)
 	for i := range lfs {
-		nb.classCases[lfs[i].Class]++
-		nb.trainFeatures(lfs[i])
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if lfs[i].CaseWeight() == 0 {
+			continue
+		}
+		for _, f := range lfs[i].Features {
+			if _, ok := st.binning[f.Name]; !ok {
+				continue
+			}
+			if _, ok := st.boundaries[f.Name]; ok {
+				continue
+			}
+			x, err := parseBinned(f)
+			if err != nil {
+				return nil, err
+			}
+			vals[f.Name] = append(vals[f.Name], discretize.Value{
+				X:      x,
+				Class:  lfs[i].Class,
+				Weight: lfs[i].CaseWeight(),
+			})
+		}
+	}
+
+	res := make(map[ft.Name][]float64, len(vals))
+	for name, v := range vals {
+		b := st.binning[name].Boundaries(v)
+		if b == nil {
+			b = []float64{}
+		}
+		res[name] = b
+	}
+	return res, nil
+}
+
+// Train adds classified feature sets to the training data. It is safe to
+// call Train many times, including after Load, every call adds new cases to
+// already accumulated data. Training data split into several batches gives
+// the same result as the training data processed at once.
+// Feature sets can have weights. Feature sets with zero weight are skipped.
+// If any weight is negative or not finite, Train returns an error and the
+// training data stays unchanged.
+func (nb *bayes) Train(lfs []ft.ClassFeatures) error {
+	return nb.TrainContext(context.Background(), lfs)
+}
+
+// TrainContext is the same as Train, but it stops if the context is
+// cancelled. The feature sets are added to the training data all at once,
+// so if the context is cancelled, the training data stay unchanged and the
+// context's error is returned.
+func (nb *bayes) TrainContext(
+	ctx context.Context,
+	lfs []ft.ClassFeatures,
+) error {
+	nb.mu.RLock()
+	st, err := nb.settings(), nb.err
+	nb.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	c, err := newCounts(ctx, lfs, st, true)
+	if err != nil {
+		return err
+	}
+
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	// settings were changed by another call, the batch has to be counted
+	// again.
+	if nb.version != st.version {
+		if c, err = newCounts(ctx, lfs, nb.settings(), true); err != nil {
+			return err
+		}
+	}
+	nb.add(c)
+	return nil
+}
+
+// Untrain removes classified feature sets from the training data, for
+// example when some of the training data turned out to be mislabeled.
+// Feature sets must have the same weights they had during training.
+// Classes and features that have no cases left are removed completely.
+// If the removal would make any count negative, or would remove all cases
+// of a class while some of its features stay, Untrain returns an error and
+// the training data stays unchanged.
+func (nb *bayes) Untrain(lfs []ft.ClassFeatures) error {
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+
+	if nb.err != nil {
+		return nb.err
+	}
+	c, err := newCounts(context.Background(), lfs, nb.settings(), false)
+	if err != nil {
+		return err
+	}
+
+	if err = nb.checkRemove(c); err != nil {
+		return err
+	}
+	nb.remove(c)
+	return nil
+}
+
+func (nb *bayes) add(c counts) {
+	if len(c.boundaries) > 0 {
+		nb.boundaries = maps.Clone(nb.boundaries)
+		maps.Copy(nb.boundaries, c.boundaries)
+		nb.version++
+	}
+
+	for _, class := range c.classes {
+		if _, ok := nb.classCases[class]; !ok {
+			nb.classes = append(nb.classes, class)
+		}
+		nb.classCases[class] += c.classCases[class]
+	}
+	nb.casesTotal += c.casesTotal
+
+	for f, fv := range c.featureCases {
+		nb.vocabRemove(f)
+		if _, ok := nb.featureCases[f]; !ok {
+			nb.featureCases[f] = make(map[ft.Class]float64)
+		}
+		for class, v := range fv {
+			nb.featureCases[f][class] += v
+			nb.featureTotal[f] += v
+		}
+		nb.vocabAdd(f)
+	}
+
+	for name, sv := range c.numStats {
+		if _, ok := nb.numStats[name]; !ok {
+			nb.numStats[name] = make(map[ft.Class]distribution.Stats)
+		}
+		for class, st := range sv {
+			nb.numStats[name][class] = nb.numStats[name][class].Add(st)
+		}
+	}
+
+	nb.updateNumRest()
+	nb.updateComplement()
+}
+
+func (nb *bayes) checkRemove(c counts) error {
+	for _, class := range c.classes {
+		if have := nb.classCases[class]; have < c.classCases[class]-epsilon {
+			return fmt.Errorf(
+				"cannot remove %g cases of class '%s', there are only %g",
+				c.classCases[class], class, have,
+			)
+		}
 	}
 
-	nb.classes = make([]ft.Class, len(nb.classCases))
-	var count int
-	for k, v := range nb.classCases {
-		nb.classes[count] = k
-		nb.casesTotal += v
-		count++
+	for f, fv := range c.featureCases {
+		for class, v := range fv {
+			if have := nb.featureCases[f][class]; have < v-epsilon {
+				return fmt.Errorf(
+					"cannot remove %g cases of feature with name '%s' and "+
+						"value '%s' from class '%s', there are only %g",
+					v, f.Name, f.Value, class, have,
+				)
+			}
+		}
 	}
-	nb.featTotal()
+
+	for name, sv := range c.numStats {
+		for class, st := range sv {
+			if err := nb.numStats[name][class].Contains(st, epsilon); err != nil {
+				return fmt.Errorf(
+					"cannot remove numeric feature '%s' from class '%s': %w",
+					name, class, err,
+				)
+			}
+		}
+	}
+
+	for _, class := range c.classes {
+		if nb.classCases[class]-c.classCases[class] <= epsilon {
+			if err := nb.checkEmptied(c, class); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-func (nb *bayes) trainFeatures(lf ft.ClassFeatures) {
-	for _, v := range lf.Features {
-		if _, ok := nb.featureCases[v]; !ok {
-			nb.featureCases[v] = make(map[ft.Class]int)
+// checkEmptied makes sure that no features of a class remain after all
+// cases of the class are removed.
+func (nb *bayes) checkEmptied(c counts, class ft.Class) error {
+	for f, fv := range nb.featureCases {
+		have, ok := fv[class]
+		if !ok || have-c.featureCases[f][class] <= epsilon {
+			continue
+		}
+		return fmt.Errorf(
+			"cannot remove all cases of class '%s', feature with name '%s' "+
+				"and value '%s' still has %g cases of the class",
+			class, f.Name, f.Value, have-c.featureCases[f][class],
+		)
+	}
+	for name, sv := range nb.numStats {
+		st, ok := sv[class]
+		if !ok || st.Count-c.numStats[name][class].Count <= epsilon {
+			continue
 		}
-		nb.featureCases[v][lf.Class]++
+		return fmt.Errorf(
+			"cannot remove all cases of class '%s', numeric feature '%s' "+
+				"still has values of the class",
+			class, name,
+		)
 	}
+	return nil
+}
+
+func (nb *bayes) remove(c counts) {
+	for _, class := range c.classes {
+		nb.classCases[class] -= c.classCases[class]
+		if nb.classCases[class] <= epsilon {
+			delete(nb.classCases, class)
+			nb.classes = slices.DeleteFunc(nb.classes, func(l ft.Class) bool {
+				return l == class
+			})
+		}
+	}
+	nb.casesTotal -= c.casesTotal
+	if nb.casesTotal <= epsilon {
+		nb.casesTotal = 0
+	}
+
+	for f, fv := range c.featureCases {
+		nb.vocabRemove(f)
+		for class, v := range fv {
+			nb.featureCases[f][class] -= v
+			if nb.featureCases[f][class] <= epsilon {
+				delete(nb.featureCases[f], class)
+			}
+			nb.featureTotal[f] -= v
+		}
+		if len(nb.featureCases[f]) == 0 {
+			delete(nb.featureCases, f)
+			delete(nb.featureTotal, f)
+		}
+		nb.vocabAdd(f)
+	}
+
+	for name, sv := range c.numStats {
+		for class, st := range sv {
+			rest := nb.numStats[name][class].Sub(st, epsilon)
+			if rest.Count <= epsilon {
+				delete(nb.numStats[name], class)
+				continue
+			}
+			nb.numStats[name][class] = rest
+		}
+		if len(nb.numStats[name]) == 0 {
+			delete(nb.numStats, name)
+		}
+	}
+
+	nb.updateNumRest()
+	nb.updateComplement()
 }
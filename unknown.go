@@ -0,0 +1,54 @@
+package bayes
+
+import (
+	"fmt"
+	"strings"
+
+	ft "github.com/gnames/bayes/ent/feature"
+)
+
+// UnknownPolicy defines how classification treats features that are not
+// known from training.
+type UnknownPolicy int
+
+const (
+	// SkipUnknown ignores unknown features. If all features are unknown,
+	// classification returns an error. It is the default policy.
+	SkipUnknown UnknownPolicy = iota
+
+	// ErrorUnknown returns an error if any feature is unknown.
+	ErrorUnknown
+
+	// NeutralUnknown treats unknown features as known features that do
+	// not change odds of any class.
+	NeutralUnknown
+
+	// BackoffUnknown estimates probabilities of unknown features by the
+	// smoothing algorithm, as if they were seen zero times in every class.
+	// Unknown numeric features are treated as neutral. Multinomial and
+	// Complement models treat features with unknown names as neutral too.
+	BackoffUnknown
+)
+
+// backoff returns true if probabilities of an unknown feature can be
+// estimated by smoothing. Multinomial and Complement models estimate them
+// from occurrences of features with the same name, which are absent for
+// unknown names.
+func (nb *bayes) backoff(f ft.Feature) bool {
+	if nb.model == Categorical {
+		return true
+	}
+	if _, ok := nb.bernoulli[f.Name]; ok {
+		return true
+	}
+	return nb.vocab.tokensTotal[f.Name] > epsilon
+}
+
+// unknownError returns an error that lists unknown features.
+func unknownError(fs []ft.Feature) error {
+	names := make([]string, len(fs))
+	for i, f := range fs {
+		names[i] = fmt.Sprintf("'%s: %s'", f.Name, f.Value)
+	}
+	return fmt.Errorf("unknown features %s", strings.Join(names, ", "))
+}
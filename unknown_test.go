@@ -0,0 +1,89 @@
+package bayes_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gnames/bayes"
+	ft "github.com/gnames/bayes/ent/feature"
+	"github.com/gnames/bayes/ent/smoothing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknown(t *testing.T) {
+	blue := ft.Feature{Name: "ColorF", Value: "blue"}
+	fs := []ft.Feature{{Name: "CookieF", Value: "chocolate"}, blue, blue}
+
+	t.Run("reports unknown features", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		p, err := nb.PosteriorOdds(fs)
+		assert.Nil(t, err)
+		assert.Equal(t, []ft.Feature{blue}, p.Unknown)
+		assert.InDelta(t, 1.0/3, p.Coverage, 1e-9)
+
+		p, err = nb.PosteriorOdds(fs[:1])
+		assert.Nil(t, err)
+		assert.Nil(t, p.Unknown)
+		assert.Equal(t, 1.0, p.Coverage)
+	})
+
+	t.Run("returns error for unknown features", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		_, err := nb.PosteriorOdds(fs, bayes.OptUnknown(bayes.ErrorUnknown))
+		assert.EqualError(t, err, "unknown features 'ColorF: blue'")
+
+		_, err = nb.PosteriorOdds(fs[:1], bayes.OptUnknown(bayes.ErrorUnknown))
+		assert.Nil(t, err)
+	})
+
+	t.Run("treats unknown features as neutral", func(t *testing.T) {
+		nb := bayes.New()
+		nb.Train(cookieJarsFeatures())
+		_, err := nb.PosteriorOdds(fs[1:])
+		assert.EqualError(t, err, "all features are unknown")
+
+		opt := bayes.OptUnknown(bayes.NeutralUnknown)
+		p, err := nb.PosteriorOdds(fs[1:], opt)
+		assert.Nil(t, err)
+		assert.InDelta(t, math.Log(0.75), p.LogOdds["Jar2"], 1e-9)
+		assert.Equal(t, 0.0, p.Coverage)
+	})
+
+	t.Run("backs off to smoothing", func(t *testing.T) {
+		nb := bayes.New(bayes.OptSmoothing(smoothing.Laplace{}))
+		nb.Train(cookieJarsFeatures())
+
+		// unknown value is smoothed to 1 of 30 cases in Jar2 and 1 of 40
+		// cases in Jar1, which cancels the prior odds 30/40.
+		opt := bayes.OptUnknown(bayes.BackoffUnknown)
+		p, err := nb.PosteriorOdds(fs[1:2], opt)
+		assert.Nil(t, err)
+		assert.InDelta(t, 0.0, p.LogOdds["Jar2"], 1e-9)
+		assert.InDelta(t, math.Log(4.0/3), p.LogLikelihoods["Jar2"][blue], 1e-9)
+	})
+
+	t.Run("backs off with multinomial model", func(t *testing.T) {
+		nb := bayes.New(
+			bayes.OptModel(bayes.Multinomial),
+			bayes.OptSmoothing(smoothing.Laplace{}),
+		)
+		nb.Train(textFeatures())
+		opt := bayes.OptUnknown(bayes.BackoffUnknown)
+
+		// there are no words with unknown names, so they are neutral.
+		p, err := nb.PosteriorOdds(fs[1:2], opt)
+		assert.Nil(t, err)
+		assert.InDelta(t, 0.0, p.LogOdds["spam"], 1e-9)
+		assert.InDelta(t, 0.0, p.LogLikelihoods["spam"][blue], 1e-9)
+
+		// unknown word is smoothed to 1 of 5+5 words in both classes.
+		sell := ft.Feature{Name: "word", Value: "sell"}
+		p, err = nb.PosteriorOdds([]ft.Feature{sell, blue}, opt)
+		assert.Nil(t, err)
+		assert.InDelta(t, 0.0, p.LogLikelihoods["spam"][sell], 1e-9)
+		assert.False(t, math.IsNaN(p.LogOdds["spam"]))
+		assert.Equal(t, 0.0, p.Coverage)
+	})
+}
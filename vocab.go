@@ -0,0 +1,91 @@
+package bayes
+
+import (
+	ft "github.com/gnames/bayes/ent/feature"
+)
+
+// vocabulary keeps statistics about values of feature names. Smoothing
+// algorithms use these statistics to estimate probabilities of features.
+type vocabulary struct {
+	// values is the number of known values for every feature name.
+	values map[ft.Name]int
+
+	// known are known values for every feature name.
+	known map[ft.Name]map[ft.Value]struct{}
+
+	// seen is the number of values of a feature name that occurred
+	// with a class.
+	seen map[ft.Name]map[ft.Class]int
+
+	// exclusive is the number of values of a feature name that occurred
+	// only with one class.
+	exclusive map[ft.Name]map[ft.Class]int
+
+	// tokens is the number of occurrences of all values of a feature name
+	// with a class.
+	tokens map[ft.Name]map[ft.Class]float64
+
+	// tokensTotal is the number of occurrences of all values of a feature
+	// name.
+	tokensTotal map[ft.Name]float64
+}
+
+func newVocabulary() vocabulary {
+	return vocabulary{
+		values:    make(map[ft.Name]int),
+		known:     make(map[ft.Name]map[ft.Value]struct{}),
+		seen:      make(map[ft.Name]map[ft.Class]int),
+		exclusive: make(map[ft.Name]map[ft.Class]int),
+
+		tokens:      make(map[ft.Name]map[ft.Class]float64),
+		tokensTotal: make(map[ft.Name]float64),
+	}
+}
+
+// seenRest returns the number of values of a feature name that occurred
+// with any class except the given one.
+func (v vocabulary) seenRest(name ft.Name, class ft.Class) int {
+	return v.values[name] - v.exclusive[name][class]
+}
+
+// vocabAdd adds the contribution of a feature to the vocabulary.
+func (nb *bayes) vocabAdd(f ft.Feature) {
+	nb.vocabUpdate(f, 1)
+}
+
+// vocabRemove removes the contribution of a feature from the vocabulary.
+// It has to be called before changing the counts of the feature.
+func (nb *bayes) vocabRemove(f ft.Feature) {
+	nb.vocabUpdate(f, -1)
+}
+
+func (nb *bayes) vocabUpdate(f ft.Feature, delta int) {
+	fv, ok := nb.featureCases[f]
+	if !ok || len(fv) == 0 {
+		return
+	}
+	v := nb.vocab
+	if _, ok = v.seen[f.Name]; !ok {
+		v.seen[f.Name] = make(map[ft.Class]int)
+		v.exclusive[f.Name] = make(map[ft.Class]int)
+		v.tokens[f.Name] = make(map[ft.Class]float64)
+	}
+
+	v.values[f.Name] += delta
+	if delta > 0 {
+		if _, ok = v.known[f.Name]; !ok {
+			v.known[f.Name] = make(map[ft.Value]struct{})
+		}
+		v.known[f.Name][f.Value] = struct{}{}
+	} else {
+		delete(v.known[f.Name], f.Value)
+	}
+	for class, count := range fv {
+		v.seen[f.Name][class] += delta
+		v.tokens[f.Name][class] += float64(delta) * count
+		v.tokensTotal[f.Name] += float64(delta) * count
+		if len(fv) == 1 {
+			v.exclusive[f.Name][class] += delta
+		}
+	}
+}